@@ -0,0 +1,71 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDragLayout(t testing.TB, options ...SolverOption) (*Solver, *Variable) {
+	solver := NewSolver(options...)
+	left := NewVariable("left")
+	width := NewVariable("width")
+	right := NewVariable("right")
+	handle := NewVariable("handle")
+
+	for _, c := range []*Constraint{
+		right.EqualsExpression(left.Add(width)),
+		width.GreaterThanOrEqualToFloat(50),
+		width.EqualsFloat(300).NewModifyStrength(Weak),
+		left.GreaterThanOrEqualToFloat(0),
+		right.LessThanOrEqualToFloat(800),
+		handle.EqualsExpression(left.AddExpression(NewExpressionFrom(width.Divide(2)))),
+	} {
+		assert.NoError(t, solver.AddConstraint(c))
+	}
+	assert.NoError(t, solver.AddEditVariable(handle, Strong))
+	return solver, handle
+}
+
+// Drag the handle back and forth. The positions hit both bounds of the
+// layout, so the drag pivots through several bases.
+func dragFunc(solver *Solver, handle *Variable) func() {
+	positions := []float64{0, 100, 400, 790, 20, 650}
+	i := 0
+	return func() {
+		solver.SuggestValue(handle, positions[i%len(positions)])
+		solver.UpdateVariables()
+		i++
+	}
+}
+
+func TestSuggestValueDoesNotAllocate(t *testing.T) {
+	for name, options := range map[string][]SolverOption{
+		"default":        nil,
+		"implicit stays": {WithImplicitStays()},
+		"auto refresh":   {WithAutoRefresh(1000000, 0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			solver, handle := newDragLayout(t, options...)
+			drag := dragFunc(solver, handle)
+			for i := 0; i < 10; i++ {
+				drag()
+			}
+			assert.Equal(t, float64(0), testing.AllocsPerRun(100, drag))
+		})
+	}
+}
+
+func BenchmarkSuggestValueDrag(b *testing.B) {
+	solver, handle := newDragLayout(b)
+	drag := dragFunc(solver, handle)
+	for i := 0; i < 10; i++ {
+		drag()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		drag()
+	}
+}
@@ -0,0 +1,174 @@
+package cassgowary
+
+import (
+	"math"
+)
+
+// AmbiguousVariables returns the variables whose value is not determined
+// by the constraints. A variable is ambiguous if it is parametric, or if
+// its value could change without changing the value of the objective,
+// in which case the solver just picked one of several optimal solutions.
+func (s *Solver) AmbiguousVariables() []*Variable {
+	ambiguous := []*Variable{}
+	s.vars.Each(func(k, v interface{}) {
+		variable, sym := k.(*Variable), v.(*symbol)
+		if s.isAmbiguous(variable, sym) {
+			ambiguous = append(ambiguous, variable)
+		}
+	})
+	return ambiguous
+}
+
+// ExerciseAmbiguity moves every ambiguous variable to an alternative
+// optimal solution, much like Auto Layout's exerciseAmbiguity does.
+// The objective keeps its value, so every constraint is as satisfied as
+// before, but the ambiguous variables will show different values after
+// the next UpdateVariables. An error means the tableau couldn't be made
+// feasible again after a pivot.
+func (s *Solver) ExerciseAmbiguity() error {
+	ambiguous := s.AmbiguousVariables()
+	values := make([]float64, len(ambiguous))
+	for i, variable := range ambiguous {
+		values[i] = s.currentValue(variable)
+	}
+
+	for i, variable := range ambiguous {
+		x, exists := s.owners.Get(variable)
+		if !exists {
+			continue
+		}
+		// An earlier pivot may already have moved this variable.
+		if !FloatEquals(values[i], s.currentValue(variable)) {
+			continue
+		}
+		cp := x.(*component)
+		sym := s.varSymbol(variable)
+
+		var candidates symbols
+		if r, exists := cp.rows.Get(sym); exists {
+			candidates = cp.neutralSymbols(r)
+		} else {
+			candidates = symbols{sym}
+		}
+
+		for _, entering := range candidates {
+			moved, err := cp.exerciseSymbol(entering)
+			if err != nil {
+				return err
+			}
+			if moved {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Get the value the variable has in the current tableau.
+func (s *Solver) currentValue(variable *Variable) float64 {
+	x, exists := s.owners.Get(variable)
+	if !exists {
+		return 0
+	}
+	if r, exists := x.(*component).rows.Get(s.varSymbol(variable)); exists {
+		return r.constant
+	}
+	return 0
+}
+
+func (s *Solver) isAmbiguous(variable *Variable, sym *symbol) bool {
+	x, exists := s.owners.Get(variable)
+	if !exists {
+		return true
+	}
+	cp := x.(*component)
+
+	r, exists := cp.rows.Get(sym)
+	if !exists {
+		return true
+	}
+
+	for _, entering := range cp.neutralSymbols(r) {
+		for _, direction := range directionsFor(entering) {
+			if bound, _, _ := cp.alternativeStep(entering, direction); bound > Epsilon {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get the parametric symbols of the row which can enter the basis
+// without changing the value of the objective.
+func (cp *component) neutralSymbols(r *row) symbols {
+	neutral := symbols{}
+	r.cells.Each(func(sym *symbol, _ float64) {
+		if sym.kind != symbolDummy && FloatNearZero(cp.objective.coefficientFor(sym)) {
+			neutral = append(neutral, sym)
+		}
+	})
+	return neutral
+}
+
+// Pivot the symbol into the basis at the nearest alternative vertex.
+// This returns false if the symbol can't move in any direction.
+func (cp *component) exerciseSymbol(entering *symbol) (bool, error) {
+	for _, direction := range directionsFor(entering) {
+		bound, leaving, step := cp.alternativeStep(entering, direction)
+		if bound <= Epsilon || leaving == nil || step <= Epsilon {
+			continue
+		}
+
+		r, _ := cp.rows.Get(leaving)
+		cp.pivot(entering, leaving, r, step)
+		return true, cp.dualOptimize()
+	}
+	return false, nil
+}
+
+// Compute how far the parametric symbol can move in the given direction.
+// The bound is the largest step that keeps every restricted row feasible.
+// The leaving symbol and step describe the nearest vertex in that
+// direction, which may also be an external row reaching zero.
+func (cp *component) alternativeStep(entering *symbol, direction float64) (bound float64, leaving *symbol, step float64) {
+	bound, step = math.Inf(1), math.Inf(1)
+
+	cp.rows.Each(func(sym *symbol, r *row) {
+
+		coefficient := r.coefficientFor(entering) * direction
+		if coefficient == 0 {
+			return
+		}
+
+		var ratio float64
+		if sym.kind == symbolExternal {
+			ratio = -r.constant / coefficient
+			if ratio <= Epsilon {
+				return
+			}
+		} else {
+			if coefficient > 0 {
+				return
+			}
+			ratio = -r.constant / coefficient
+			if ratio < bound {
+				bound = ratio
+			}
+		}
+
+		if ratio < step {
+			step = ratio
+			leaving = sym
+		}
+	})
+
+	return bound, leaving, step
+}
+
+// Restricted symbols can only grow, external ones move both ways.
+func directionsFor(sym *symbol) []float64 {
+	if sym.kind == symbolExternal {
+		return []float64{1, -1}
+	}
+	return []float64{1}
+}
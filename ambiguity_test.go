@@ -0,0 +1,61 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmbiguousVariablesDetermined(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(10)))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(5))))
+	assert.Empty(t, solver.AmbiguousVariables())
+}
+
+func TestAmbiguousVariablesUnderconstrained(t *testing.T) {
+	solver := NewSolver()
+	left := NewVariable("left")
+	right := NewVariable("right")
+	width := NewVariable("width")
+
+	assert.NoError(t, solver.AddConstraint(width.EqualsFloat(100)))
+	assert.NoError(t, solver.AddConstraint(right.EqualsExpression(left.Add(width))))
+	assert.NoError(t, solver.AddConstraint(left.GreaterThanOrEqualToFloat(0)))
+	assert.NoError(t, solver.AddConstraint(right.LessThanOrEqualToFloat(300)))
+
+	ambiguous := solver.AmbiguousVariables()
+	assert.Contains(t, ambiguous, left)
+	assert.Contains(t, ambiguous, right)
+	assert.NotContains(t, ambiguous, width)
+
+	solver.UpdateVariables()
+	before := left.Value
+
+	assert.NoError(t, solver.ExerciseAmbiguity())
+	solver.UpdateVariables()
+
+	assert.NotEqual(t, before, left.Value)
+	assert.InDelta(t, 100, width.Value, Epsilon)
+	assert.InDelta(t, right.Value, left.Value+width.Value, Epsilon)
+	assert.True(t, left.Value >= -Epsilon)
+	assert.True(t, right.Value <= 300+Epsilon)
+}
+
+func TestAmbiguousVariablesWithoutConstraints(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	c := x.EqualsFloat(10)
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.NoError(t, solver.AddConstraint(y.GreaterThanOrEqualToFloat(0)))
+	assert.Equal(t, []*Variable{y}, solver.AmbiguousVariables())
+
+	// Once nothing mentions x the solver forgets it.
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, []*Variable{y}, solver.AmbiguousVariables())
+}
@@ -174,9 +174,10 @@ func TestInconsistent1(t *testing.T) {
 	err := solver.AddConstraint(x.EqualsFloat(10))
 	assert.NoError(t, err)
 	err = solver.AddConstraint(x.EqualsFloat(5))
-	assert.NoError(t, err)
+	assert.Error(t, err)
 
 	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
 }
 
 func TestInconsistent2(t *testing.T) {
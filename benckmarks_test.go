@@ -2,7 +2,6 @@ package cassgowary
 
 import (
 	"fmt"
-	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,10 +9,7 @@ import (
 
 func TestBenchmarkTestAddingLotsOfConstraints(t *testing.T) {
 	solver := NewSolver()
-	vr := &benchmarkVariableResolver{
-		solver:    solver,
-		variables: nodeMap{},
-	}
+	vr := MapResolver{}
 
 	cp := NewConstraintParser()
 	c, err := cp.ParseConstraint("variable0 == 100", vr)
@@ -46,28 +42,3 @@ func TestBenchmarkTestAddingLotsOfConstraints(t *testing.T) {
 		}
 	}
 }
-
-type benchmarkVariableResolver struct {
-	solver    *Solver
-	variables nodeMap
-}
-
-func (vr *benchmarkVariableResolver) ResolveVariable(variableName string) (*Variable, error) {
-	if v, exists := vr.variables[variableName]; exists {
-		return v, nil
-	}
-
-	v := NewVariable(variableName)
-	vr.variables[variableName] = v
-	return v, nil
-}
-
-func (vr *benchmarkVariableResolver) ResolveConstant(name string) (*Expression, error) {
-	f, err := strconv.ParseFloat(name, 64)
-	if err != nil {
-		return nil, err
-	}
-
-	e := NewExpression(f)
-	return e, nil
-}
@@ -0,0 +1,204 @@
+package cassgowary
+
+// cellMap is an insertion ordered map from symbols to coefficients.
+// Removing an entry leaves a hole which is compacted away once the holes
+// outnumber the live entries, so once the map has grown to its working
+// size neither insertion nor removal allocates.
+type cellMap struct {
+	keys      []*symbol
+	values    []float64
+	positions map[*symbol]int
+	holes     int
+}
+
+func newCellMap() *cellMap {
+	return &cellMap{
+		positions: map[*symbol]int{},
+	}
+}
+
+func (m *cellMap) Get(s *symbol) (float64, bool) {
+	if i, exists := m.positions[s]; exists {
+		return m.values[i], true
+	}
+	return 0, false
+}
+
+func (m *cellMap) Put(s *symbol, value float64) {
+	if i, exists := m.positions[s]; exists {
+		m.values[i] = value
+		return
+	}
+	m.positions[s] = len(m.keys)
+	m.keys = append(m.keys, s)
+	m.values = append(m.values, value)
+}
+
+func (m *cellMap) Remove(s *symbol) {
+	i, exists := m.positions[s]
+	if !exists {
+		return
+	}
+	delete(m.positions, s)
+	m.keys[i] = nil
+	m.holes++
+	if m.holes > len(m.positions) {
+		m.compact()
+	}
+}
+
+// Move the live entries to the front, keeping their order.
+func (m *cellMap) compact() {
+	n := 0
+	for i, s := range m.keys {
+		if s == nil {
+			continue
+		}
+		m.keys[n], m.values[n] = s, m.values[i]
+		m.positions[s] = n
+		n++
+	}
+	for i := n; i < len(m.keys); i++ {
+		m.keys[i] = nil
+	}
+	m.keys, m.values = m.keys[:n], m.values[:n]
+	m.holes = 0
+}
+
+func (m *cellMap) Size() int {
+	return len(m.positions)
+}
+
+func (m *cellMap) Clear() {
+	for s := range m.positions {
+		delete(m.positions, s)
+	}
+	for i := range m.keys {
+		m.keys[i] = nil
+	}
+	m.keys, m.values = m.keys[:0], m.values[:0]
+	m.holes = 0
+}
+
+// Each calls f for every entry in insertion order.
+// f may change the value of the current entry through Put.
+func (m *cellMap) Each(f func(s *symbol, value float64)) {
+	for i := 0; i < len(m.keys); i++ {
+		if s := m.keys[i]; s != nil {
+			f(s, m.values[i])
+		}
+	}
+}
+
+// Find returns the first symbol for which f returns true, or nil.
+func (m *cellMap) Find(f func(s *symbol, value float64) bool) *symbol {
+	for i, s := range m.keys {
+		if s != nil && f(s, m.values[i]) {
+			return s
+		}
+	}
+	return nil
+}
+
+// All returns true if f returns true for every entry.
+func (m *cellMap) All(f func(s *symbol, value float64) bool) bool {
+	return m.Find(func(s *symbol, value float64) bool {
+		return !f(s, value)
+	}) == nil
+}
+
+// Multiply every value in place.
+func (m *cellMap) scale(factor float64) {
+	for i, s := range m.keys {
+		if s != nil {
+			m.values[i] *= factor
+		}
+	}
+}
+
+// rowMap is an insertion ordered map from basic symbols to their rows,
+// built the same way as cellMap.
+type rowMap struct {
+	keys      []*symbol
+	values    []*row
+	positions map[*symbol]int
+	holes     int
+}
+
+func newRowMap() *rowMap {
+	return &rowMap{
+		positions: map[*symbol]int{},
+	}
+}
+
+func (m *rowMap) Get(s *symbol) (*row, bool) {
+	if i, exists := m.positions[s]; exists {
+		return m.values[i], true
+	}
+	return nil, false
+}
+
+func (m *rowMap) Put(s *symbol, r *row) {
+	if i, exists := m.positions[s]; exists {
+		m.values[i] = r
+		return
+	}
+	m.positions[s] = len(m.keys)
+	m.keys = append(m.keys, s)
+	m.values = append(m.values, r)
+}
+
+func (m *rowMap) Remove(s *symbol) {
+	i, exists := m.positions[s]
+	if !exists {
+		return
+	}
+	delete(m.positions, s)
+	m.keys[i], m.values[i] = nil, nil
+	m.holes++
+	if m.holes > len(m.positions) {
+		m.compact()
+	}
+}
+
+// Move the live entries to the front, keeping their order.
+func (m *rowMap) compact() {
+	n := 0
+	for i, s := range m.keys {
+		if s == nil {
+			continue
+		}
+		m.keys[n], m.values[n] = s, m.values[i]
+		m.positions[s] = n
+		n++
+	}
+	for i := n; i < len(m.keys); i++ {
+		m.keys[i], m.values[i] = nil, nil
+	}
+	m.keys, m.values = m.keys[:n], m.values[:n]
+	m.holes = 0
+}
+
+func (m *rowMap) Size() int {
+	return len(m.positions)
+}
+
+func (m *rowMap) Clear() {
+	for s := range m.positions {
+		delete(m.positions, s)
+	}
+	for i := range m.keys {
+		m.keys[i], m.values[i] = nil, nil
+	}
+	m.keys, m.values = m.keys[:0], m.values[:0]
+	m.holes = 0
+}
+
+// Each calls f for every row in insertion order.
+func (m *rowMap) Each(f func(s *symbol, r *row)) {
+	for i := 0; i < len(m.keys); i++ {
+		if s := m.keys[i]; s != nil {
+			f(s, m.values[i])
+		}
+	}
+}
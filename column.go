@@ -0,0 +1,86 @@
+package cassgowary
+
+// A column holds the rows of the tableau which contain a symbol.
+// Rows are removed by swapping in the last one, so both adding and
+// removing a row are constant time.
+type column struct {
+	rows      []*row
+	positions map[*row]int
+}
+
+// columnIndex maps every parametric symbol of a tableau to its column.
+// The rows keep it up to date whenever a cell is added or removed, so
+// substitutions and ratio tests only have to visit the rows which
+// actually contain the symbol. Empty columns are kept for reuse, since
+// pivots keep moving symbols in and out of the basis.
+type columnIndex struct {
+	columns map[*symbol]*column
+	free    []*column
+}
+
+func newColumnIndex() *columnIndex {
+	return &columnIndex{
+		columns: map[*symbol]*column{},
+	}
+}
+
+func (ci *columnIndex) add(s *symbol, r *row) {
+	col, exists := ci.columns[s]
+	if !exists {
+		if n := len(ci.free); n > 0 {
+			col = ci.free[n-1]
+			ci.free[n-1] = nil
+			ci.free = ci.free[:n-1]
+		} else {
+			col = &column{positions: map[*row]int{}}
+		}
+		ci.columns[s] = col
+	}
+	if _, exists := col.positions[r]; exists {
+		return
+	}
+	col.positions[r] = len(col.rows)
+	col.rows = append(col.rows, r)
+}
+
+func (ci *columnIndex) remove(s *symbol, r *row) {
+	col, exists := ci.columns[s]
+	if !exists {
+		return
+	}
+	i, exists := col.positions[r]
+	if !exists {
+		return
+	}
+
+	last := len(col.rows) - 1
+	col.rows[i] = col.rows[last]
+	col.positions[col.rows[i]] = i
+	col.rows[last] = nil
+	col.rows = col.rows[:last]
+	delete(col.positions, r)
+
+	if len(col.rows) == 0 {
+		delete(ci.columns, s)
+		ci.free = append(ci.free, col)
+	}
+}
+
+// Get the rows of the tableau which contain the symbol.
+// The slice must not be kept across changes to the tableau.
+func (ci *columnIndex) rowsWith(s *symbol) []*row {
+	if col, exists := ci.columns[s]; exists {
+		return col.rows
+	}
+	return nil
+}
+
+// Get the last row of the tableau which contains the symbol, or nil.
+// Removing the symbol from the row removes the row from the column,
+// so this is how to visit a column while eliminating the symbol.
+func (ci *columnIndex) lastRowWith(s *symbol) *row {
+	if rows := ci.rowsWith(s); len(rows) > 0 {
+		return rows[len(rows)-1]
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cassgowary
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Check that the column index of every component matches its rows.
+func assertColumnsConsistent(t *testing.T, solver *Solver) {
+	solver.components.Each(func(_ int, v interface{}) {
+		cp := v.(*component)
+		cells := 0
+		cp.rows.Each(func(basic *symbol, r *row) {
+			assert.Equal(t, basic, r.basic)
+			r.cells.Each(func(s *symbol, _ float64) {
+				assert.Contains(t, cp.columns.rowsWith(s), r)
+				cells++
+			})
+		})
+
+		indexed := 0
+		for _, col := range cp.columns.columns {
+			indexed += len(col.rows)
+		}
+		assert.Equal(t, cells, indexed)
+	})
+}
+
+func TestColumnIndexFollowsTableau(t *testing.T) {
+	solver := NewSolver()
+	left := NewVariable("left")
+	width := NewVariable("width")
+	right := NewVariable("right")
+	mid := NewVariable("mid")
+
+	assert.NoError(t, solver.AddConstraint(right.EqualsExpression(left.Add(width))))
+	assert.NoError(t, solver.AddConstraint(mid.EqualsExpression(left.AddExpression(NewExpressionFrom(width.Divide(2))))))
+	assert.NoError(t, solver.AddConstraint(width.GreaterThanOrEqualToFloat(50)))
+	wide := width.EqualsFloat(300).NewModifyStrength(Weak)
+	assert.NoError(t, solver.AddConstraint(wide))
+	assert.NoError(t, solver.AddConstraint(right.LessThanOrEqualToFloat(200)))
+	assertColumnsConsistent(t, solver)
+
+	assert.NoError(t, solver.AddEditVariable(left, Strong))
+	for _, value := range []float64{0, 120, 180, 10} {
+		assert.NoError(t, solver.SuggestValue(left, value))
+		assertColumnsConsistent(t, solver)
+	}
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, left.Value, Epsilon)
+	assert.InDelta(t, 190, width.Value, Epsilon)
+	assert.InDelta(t, 105, mid.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveConstraint(wide))
+	assertColumnsConsistent(t, solver)
+	assert.NoError(t, solver.RemoveEditVariable(left))
+	assertColumnsConsistent(t, solver)
+}
+
+func BenchmarkSuggestValueLargeLayout(b *testing.B) {
+	solver := NewSolver()
+	vars := make([]*Variable, 1000)
+	for i := range vars {
+		vars[i] = NewVariable(fmt.Sprintf("v%d", i))
+		if i == 0 {
+			continue
+		}
+		if err := solver.AddConstraint(vars[i].GreaterThanOrEqualToExpression(vars[i-1].AddFloat(10))); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := solver.AddEditVariable(vars[0], Strong); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := solver.SuggestValue(vars[0], float64(i%100)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package cassgowary
+
+import (
+	"math"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/emirpasic/gods/sets/linkedhashset"
+	"github.com/pkg/errors"
+)
+
+// Pivot elements smaller than pivotTolerance are only chosen when no
+// other pivot is possible. ratioTolerance is how far the Harris ratio
+// test may relax the bound to find a larger pivot element.
+const (
+	pivotTolerance = 1.0e-9
+	ratioTolerance = Epsilon
+)
+
+// A component is an independent sub-tableau of the solver.
+// The constraints of one component never share a variable with the
+// constraints of another one, so pivots, substitutions and edits only
+// have to look at the rows of the component they happen in.
+type component struct {
+	cns                   *linkedhashmap.Map //[*Constraint]*tag
+	vars                  *linkedhashset.Set //*Variable
+	rows                  *rowMap
+	columns               *columnIndex
+	infeasibleRows        symbols
+	objective, artificial *row
+	solver                *Solver
+}
+
+func newComponent(s *Solver) *component {
+	return &component{
+		solver:         s,
+		cns:            linkedhashmap.New(),
+		vars:           linkedhashset.New(),
+		rows:           newRowMap(),
+		columns:        newColumnIndex(),
+		infeasibleRows: symbols{},
+		objective:      newRow(),
+		artificial:     nil,
+	}
+}
+
+// Put the row into the tableau as the row of the basic symbol.
+func (cp *component) putRow(basic *symbol, r *row) {
+	r.basic = basic
+	r.columns = cp.columns
+	r.cells.Each(func(s *symbol, _ float64) {
+		cp.columns.add(s, r)
+	})
+	cp.rows.Put(basic, r)
+}
+
+// Take the row of the basic symbol out of the tableau.
+func (cp *component) takeRow(basic *symbol) *row {
+	r, exists := cp.rows.Get(basic)
+	if !exists {
+		return nil
+	}
+	r.cells.Each(func(s *symbol, _ float64) {
+		cp.columns.remove(s, r)
+	})
+	r.basic = nil
+	r.columns = nil
+	cp.rows.Remove(basic)
+	return r
+}
+
+// Remove a symbol no constraint depends on from the tableau.
+// Its value doesn't matter to the other symbols, so fixing it at zero
+// keeps the current solution.
+func (cp *component) dropSymbol(sym *symbol) {
+	cp.takeRow(sym)
+	for r := cp.columns.lastRowWith(sym); r != nil; r = cp.columns.lastRowWith(sym) {
+		r.remove(sym)
+	}
+	cp.objective.remove(sym)
+}
+
+// Remove the constraint rows identified by the tag from the tableau.
+// A range has a row for either bound, the upper one is removed first,
+// since its slack appears in no other constraint.
+func (cp *component) removeRow(c *Constraint, t *tag) error {
+	cp.removeConstraintEffects(c, t)
+
+	if c.Op == OP_RANGE && t.other != nil && t.other.kind == symbolSlack {
+		if err := cp.removeMarker(t.other); err != nil {
+			return err
+		}
+	}
+	return cp.removeMarker(t.marker)
+}
+
+// Remove the row of the marker, pivoting the marker into the basis first
+// if it isn't basic.
+func (cp *component) removeMarker(marker *symbol) error {
+	if cp.takeRow(marker) != nil {
+		return nil
+	}
+
+	r := cp.markerLeavingRow(marker)
+	if r == nil {
+		return InternalSolverErr
+	}
+
+	cp.pivot(marker, r.basic, r, math.Abs(r.constant/r.coefficientFor(marker)))
+	// The row of the marker leaves the tableau with it.
+	cp.takeRow(marker)
+	return nil
+}
+
+func (cp *component) removeConstraintEffects(c *Constraint, t *tag) {
+	if t.marker != nil && t.marker.kind == symbolError {
+		cp.removeMarkerEffects(t.marker, t.weight(c))
+	} else if t.other != nil && t.other.kind == symbolError {
+		cp.removeMarkerEffects(t.other, t.weight(c))
+	}
+	for _, sym := range []*symbol{t.lowerError, t.upperError} {
+		if sym != nil {
+			cp.removeMarkerEffects(sym, t.weight(c))
+		}
+	}
+}
+
+func (cp *component) removeMarkerEffects(marker *symbol, strength float64) {
+	if r, exists := cp.rows.Get(marker); exists {
+		cp.objective.insertRow(r, -strength)
+	} else {
+		cp.objective.insertSymbol(marker, -strength)
+	}
+}
+
+func (cp *component) markerLeavingRow(marker *symbol) *row {
+	r1, r2 := math.MaxFloat64, math.MaxFloat64
+	var first, second, third *row
+
+	for _, candidate := range cp.columns.rowsWith(marker) {
+		c := candidate.coefficientFor(marker)
+		if c == 0 {
+			continue
+		}
+
+		if candidate.basic.kind == symbolExternal {
+			third = candidate
+		} else if c < 0 {
+			r := -candidate.constant / c
+			if r < r1 {
+				r1 = r
+				first = candidate
+			}
+		} else {
+			if r := candidate.constant / c; r < r2 {
+				r2 = r
+				second = candidate
+			}
+		}
+	}
+
+	if first != nil {
+		return first
+	}
+	if second != nil {
+		return second
+	}
+	return third
+}
+
+// Move the constant of an edit constraint to the given value and
+// re-optimize the component with the dual simplex method.
+func (cp *component) suggestValue(edit *editInfo, value float64) error {
+	delta := (value - edit.constant) * edit.tag.scale
+	edit.constant = value
+
+	if r, exists := cp.rows.Get(edit.tag.marker); exists {
+		if r.add(-delta) < 0.0 {
+			cp.infeasibleRows = append(
+				cp.infeasibleRows,
+				edit.tag.marker,
+			)
+		}
+		return cp.dualOptimize()
+	}
+
+	if r, exists := cp.rows.Get(edit.tag.other); exists {
+		if r.add(delta) < 0 {
+			cp.infeasibleRows = append(
+				cp.infeasibleRows,
+				edit.tag.other,
+			)
+		}
+		return cp.dualOptimize()
+	}
+
+	for _, r := range cp.columns.rowsWith(edit.tag.marker) {
+		coefficient := r.coefficientFor(edit.tag.marker)
+		if coefficient != 0.0 &&
+			r.add(delta*coefficient) < 0.0 &&
+			r.basic.kind != symbolExternal {
+			cp.infeasibleRows = append(
+				cp.infeasibleRows,
+				r.basic,
+			)
+		}
+	}
+
+	return cp.dualOptimize()
+}
+
+// Choose the subject for solving for the row
+// This method will choose the best subject for using as the solve
+// target for the row. An invalid symbol will be returned if there
+// is no valid target.
+// The symbols are chosen according to the following precedence:
+// 1) The first symbol representing an external variable.
+// 2) A negative slack or error tag variable.
+// If a subject cannot be found, an invalid symbol will be returned.
+func (cp *component) chooseSubject(r *row, t *tag) *symbol {
+	if fk := r.cells.Find(func(s *symbol, _ float64) bool {
+		return s.kind == symbolExternal
+	}); fk != nil {
+		return fk
+	}
+
+	if t.marker != nil && (t.marker.kind == symbolSlack || t.marker.kind == symbolError) {
+		if r.coefficientFor(t.marker) < 0.0 {
+			return t.marker
+		}
+	}
+
+	if t.other != nil && (t.other.kind == symbolSlack || t.other.kind == symbolError) {
+		if r.coefficientFor(t.other) < 0.0 {
+			return t.other
+		}
+	}
+
+	return newSymbol()
+}
+
+// Add the row to the tableau using an artificial variable.
+// This will return false if the constraint cannot be satisfied.
+func (cp *component) addWithArtificialVariable(r *row) (bool, error) {
+	// Create and add the artificial variable to the tableau
+	art := newSymbolFrom(symbolSlack)
+	cp.putRow(art, newRowFrom(r))
+	cp.artificial = newRowFrom(r)
+
+	// Optimize the artificial objective. This is successful
+	// only if the artificial objective is optimized to zero.
+	if err := cp.optimize(cp.artificial); err != nil {
+		return false, errors.Wrap(err, "can't optimize")
+	}
+
+	success := FloatNearZero(cp.artificial.constant)
+	cp.artificial = nil
+
+	// If the artificial variable is basic, pivot the row so that
+	// it becomes basic. If the row is constant, exit early.
+	if rowptr, exists := cp.rows.Get(art); exists {
+		if rowptr.cells.Size() == 0 {
+			cp.takeRow(art)
+			return success, nil
+		}
+
+		entering := cp.anyPivotableSymbol(rowptr)
+		if entering.kind == symbolInvalid {
+			cp.takeRow(art)
+			return false, nil // unsatisfiable (will this ever happen?)
+		}
+		cp.pivot(entering, art, rowptr, math.Abs(rowptr.constant/rowptr.coefficientFor(entering)))
+	}
+
+	// Remove the artificial variable from the tableau.
+	for r := cp.columns.lastRowWith(art); r != nil; r = cp.columns.lastRowWith(art) {
+		r.remove(art)
+	}
+
+	cp.objective.cells.Remove(art)
+	return success, nil
+}
+
+// Substitute the parametric symbol with the given row.
+// This method will substitute all instances of the parametric symbol
+// in the tableau and the objective function with the given row.
+func (cp *component) substitute(sym *symbol, r *row) {
+	for row := cp.columns.lastRowWith(sym); row != nil; row = cp.columns.lastRowWith(sym) {
+		row.substitute(sym, r)
+
+		if row.basic.kind != symbolExternal && row.constant < 0 {
+			cp.infeasibleRows = append(cp.infeasibleRows, row.basic)
+		}
+	}
+
+	cp.objective.substitute(sym, r)
+
+	if cp.artificial != nil {
+		cp.artificial.substitute(sym, r)
+	}
+}
+
+// Optimize the system for the given objective function.
+// This method performs iterations of Phase 2 of the simplex method
+// until the objective function reaches a minimum.
+func (cp *component) optimize(objective *row) error {
+	for {
+		entering := cp.enteringSymbol(objective)
+		if entering.kind == symbolInvalid {
+			return nil
+		}
+
+		entry, ratio := cp.leavingRow(entering)
+		if entry == nil {
+			return errors.New("The objective is unbounded.")
+		}
+
+		cp.pivot(entering, entry.basic, entry, ratio)
+	}
+}
+
+func (cp *component) dualOptimize() error {
+	for len(cp.infeasibleRows) > 0 {
+		lastIndex := len(cp.infeasibleRows) - 1
+		leaving := cp.infeasibleRows[lastIndex]
+		cp.infeasibleRows = cp.infeasibleRows[:lastIndex]
+
+		if r, exists := cp.rows.Get(leaving); exists {
+			if r.constant < 0 {
+				entering, ratio := cp.dualEnteringSymbol(r)
+				if entering.kind == symbolInvalid {
+					return InternalSolverErr
+				}
+				if cp.solver.tracer != nil {
+					cp.solver.tracer.DualIteration(leaving.String(), entering.String())
+				}
+				cp.pivot(entering, leaving, r, ratio)
+			}
+		}
+	}
+	return nil
+}
+
+// Pivot the entering symbol into the basis in place of the leaving one.
+// The row must be the row of the leaving symbol.
+func (cp *component) pivot(entering, leaving *symbol, r *row, ratio float64) {
+	if cp.solver.tracer != nil {
+		cp.solver.tracer.Pivot(entering.String(), leaving.String(), ratio)
+	}
+	cp.solver.pivots++
+
+	cp.takeRow(leaving)
+	r.solveForSymbols(leaving, entering)
+	cp.substitute(entering, r)
+	cp.putRow(entering, r)
+}
+
+// Compute the entering variable for a pivot operation.
+// This method will return first symbol in the objective function which
+// is non-dummy and has a coefficient less than zero. If no symbol meets
+// the criteria, it means the objective function is at a minimum, and an
+// invalid symbol is returned.
+func (cp *component) enteringSymbol(objective *row) *symbol {
+	if found := objective.cells.Find(func(s *symbol, value float64) bool {
+		return s.kind != symbolDummy && value < 0
+	}); found != nil {
+		return found
+	}
+
+	return newSymbol()
+}
+
+// Compute the entering symbol for a dual pivot on the row.
+// This is a Harris ratio test: the first pass finds the smallest ratio
+// relaxed by ratioTolerance, the second picks the largest pivot element
+// within that bound. Pivot elements below pivotTolerance are only used
+// if nothing else is available.
+func (cp *component) dualEnteringSymbol(r *row) (*symbol, float64) {
+	for _, threshold := range []float64{pivotTolerance, 0} {
+		bound := math.MaxFloat64
+		r.cells.Each(func(sym *symbol, currentCell float64) {
+			if sym.kind != symbolDummy && currentCell > threshold {
+				coefficient := cp.objective.coefficientFor(sym)
+				bound = math.Min(bound, (coefficient+ratioTolerance)/currentCell)
+			}
+		})
+
+		var entering *symbol
+		ratio, pivot := math.MaxFloat64, 0.0
+		r.cells.Each(func(sym *symbol, currentCell float64) {
+			if sym.kind != symbolDummy && currentCell > threshold {
+				coefficient := cp.objective.coefficientFor(sym)
+				if cr := coefficient / currentCell; cr <= bound && currentCell > pivot {
+					entering, ratio, pivot = sym, cr, currentCell
+				}
+			}
+		})
+		if entering != nil {
+			return entering, ratio
+		}
+	}
+
+	return newSymbol(), math.MaxFloat64
+}
+
+// Get the first Slack or Error symbol in the row.
+// If no such symbol is present, and Invalid symbol will be returned.
+func (cp *component) anyPivotableSymbol(r *row) *symbol {
+	if fs := r.cells.Find(func(sym *symbol, _ float64) bool {
+		return sym.kind == symbolSlack || sym.kind == symbolError
+	}); fs != nil {
+		return fs
+	}
+	return newSymbol()
+}
+
+// Compute the row which holds the exit symbol for a pivot.
+// This documentation is copied from the C++ version and is outdated
+// This method will return an iterator to the row in the row map
+// which holds the exit symbol. If no appropriate exit symbol is
+// found, the end() iterator will be returned. This indicates that
+// the objective function is unbounded.
+//
+// The ratio test is the same two-pass Harris test as in dualEnteringSymbol,
+// so among the rows which limit the entering symbol the one with the
+// largest pivot element is chosen.
+func (cp *component) leavingRow(entering *symbol) (*row, float64) {
+	for _, threshold := range []float64{pivotTolerance, 0} {
+		candidates := cp.columns.rowsWith(entering)
+		bound := math.MaxFloat64
+		for _, candidate := range candidates {
+			if candidate.basic.kind != symbolExternal {
+				if t := candidate.coefficientFor(entering); t < -threshold {
+					bound = math.Min(bound, (candidate.constant+ratioTolerance)/-t)
+				}
+			}
+		}
+
+		var r *row
+		ratio, pivot := math.MaxFloat64, 0.0
+		for _, candidate := range candidates {
+			if candidate.basic.kind != symbolExternal {
+				if t := candidate.coefficientFor(entering); t < -threshold {
+					if tr := -candidate.constant / t; tr <= bound && -t > pivot {
+						r, ratio, pivot = candidate, tr, -t
+					}
+				}
+			}
+		}
+		if r != nil {
+			return r, ratio
+		}
+	}
+	return nil, math.MaxFloat64
+}
+
+// unionFind groups variables that are connected through constraints.
+type unionFind struct {
+	parents map[*Variable]*Variable
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{
+		parents: map[*Variable]*Variable{},
+	}
+}
+
+func (uf *unionFind) contains(v interface{}) bool {
+	_, exists := uf.parents[v.(*Variable)]
+	return exists
+}
+
+func (uf *unionFind) find(v *Variable) *Variable {
+	p, exists := uf.parents[v]
+	if !exists {
+		uf.parents[v] = v
+		return v
+	}
+	if p == v {
+		return v
+	}
+	root := uf.find(p)
+	uf.parents[v] = root
+	return root
+}
+
+func (uf *unionFind) union(a, b *Variable) {
+	ra, rb := uf.find(a), uf.find(b)
+	if ra != rb {
+		uf.parents[rb] = ra
+	}
+}
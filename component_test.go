@@ -0,0 +1,85 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentsIndependent(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+	a := NewVariable("a")
+	b := NewVariable("b")
+
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(10))))
+	assert.NoError(t, solver.AddConstraint(b.EqualsExpression(a.Multiply(2).AddFloat(1))))
+	assert.Equal(t, 2, solver.components.Size())
+
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.AddEditVariable(a, Strong))
+	assert.Equal(t, 2, solver.components.Size())
+
+	assert.NoError(t, solver.SuggestValue(x, 5))
+	assert.NoError(t, solver.SuggestValue(a, 3))
+	solver.UpdateVariables()
+
+	assert.InDelta(t, 5, x.Value, Epsilon)
+	assert.InDelta(t, 15, y.Value, Epsilon)
+	assert.InDelta(t, 3, a.Value, Epsilon)
+	assert.InDelta(t, 7, b.Value, Epsilon)
+
+	xOwner, _ := solver.owners.Get(x)
+	aOwner, _ := solver.owners.Get(a)
+	assert.NotEqual(t, xOwner, aOwner)
+}
+
+func TestComponentsMergeAndSplit(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+	a := NewVariable("a")
+	b := NewVariable("b")
+
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(10))))
+	assert.NoError(t, solver.AddConstraint(b.EqualsExpression(a.AddFloat(20))))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 5))
+	assert.Equal(t, 2, solver.components.Size())
+
+	bridge := a.Equals(y)
+	assert.NoError(t, solver.AddConstraint(bridge))
+	assert.Equal(t, 1, solver.components.Size())
+
+	solver.UpdateVariables()
+	assert.InDelta(t, 5, x.Value, Epsilon)
+	assert.InDelta(t, 15, y.Value, Epsilon)
+	assert.InDelta(t, 15, a.Value, Epsilon)
+	assert.InDelta(t, 35, b.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveConstraint(bridge))
+	assert.Equal(t, 2, solver.components.Size())
+
+	assert.NoError(t, solver.AddConstraint(a.EqualsFloat(1)))
+	assert.NoError(t, solver.SuggestValue(x, 7))
+	solver.UpdateVariables()
+	assert.InDelta(t, 7, x.Value, Epsilon)
+	assert.InDelta(t, 17, y.Value, Epsilon)
+	assert.InDelta(t, 1, a.Value, Epsilon)
+	assert.InDelta(t, 21, b.Value, Epsilon)
+}
+
+func TestComponentsReleaseVariables(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	c := x.EqualsFloat(10)
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.Equal(t, 1, solver.components.Size())
+
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, 0, solver.components.Size())
+	_, exists := solver.owners.Get(x)
+	assert.False(t, exists)
+}
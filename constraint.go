@@ -8,6 +8,9 @@ type Constraint struct {
 	expression *Expression
 	Strength   Strength
 	Op         RelationalOperator
+	// span is the width of a range, which keeps the expression in
+	// [0, span].
+	span float64
 }
 
 func NewConstraintRequired(expr *Expression, op RelationalOperator) *Constraint {
@@ -23,10 +26,52 @@ func NewConstraint(expr *Expression, op RelationalOperator, strength Strength) *
 }
 
 func NewConstraintFrom(other *Constraint, s Strength) *Constraint {
-	return NewConstraint(other.expression, other.Op, s)
+	c := NewConstraint(other.expression, other.Op, s)
+	c.span = other.span
+	return c
+}
+
+// Between returns the required range constraint lo <= e <= hi. The solver
+// keeps a range as one constraint with a slack for either bound, so both
+// bounds are added and removed together.
+func Between(lo float64, e *Expression, hi float64) *Constraint {
+	return newRange(e.SubtractFloat(lo), hi-lo, Required)
+}
+
+// Get the range constraint 0 <= e <= span.
+func newRange(e *Expression, span float64, strength Strength) *Constraint {
+	c := NewConstraint(e, OP_RANGE, strength)
+	c.span = span
+	return c
+}
+
+// variables returns the distinct variables of the constraint, skipping
+// the ones whose scaled coefficient reduced to zero.
+func (c *Constraint) variables() []*Variable {
+	scale := c.scale()
+	vars := make([]*Variable, 0, len(c.expression.Terms))
+	for _, t := range c.expression.Terms {
+		if !FloatNearZero(t.Coefficient * scale) {
+			vars = append(vars, t.Variable)
+		}
+	}
+	return vars
+}
+
+// scale returns the factor the row of the constraint is scaled by.
+// The error symbols of a scaled soft row measure the scaled violation,
+// so they are weighted by the strength divided by the scale.
+func (c *Constraint) scale() float64 {
+	return rowScale(c.expression)
 }
 
 func (c *Constraint) String() string {
+	if c.Op == OP_RANGE {
+		return fmt.Sprintf(
+			"expression: (%v) strength:%f operator:%v span:%f",
+			c.expression, c.Strength, c.Op, c.span,
+		)
+	}
 	return fmt.Sprintf(
 		"expression: (%v) strength:%f operator:%v",
 		c.expression, c.Strength, c.Op,
@@ -2,227 +2,573 @@ package cassgowary
 
 import (
 	"fmt"
-	"log"
-	"regexp"
+	"math"
+	"strconv"
 	"strings"
-
-	"github.com/emirpasic/gods/stacks/arraystack"
-	"github.com/pkg/errors"
 )
 
-const constraintParserOperations = "-+/*^"
-
-type ConstraintParser struct {
-	pattern *regexp.Regexp
-}
+// ConstraintParser parses constraints written as two linear expressions
+// joined by a relation, optionally followed by a strength:
+//
+//	constraint = expression relation expression { relation expression } [ strength ]
+//	expression = term { ( "+" | "-" ) term }
+//	term       = unary { ( "*" | "/" ) unary }
+//	unary      = ( "-" | "+" ) unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | identifier | call | "(" expression ")"
+//	call       = ( "min" | "max" | "abs" ) "(" expression { "," expression } ")"
+//	strength   = "!" name [ "(" number ")" ]
+//	           | "!" number ":" number ":" number
+//	           | "@" number
+//
+// The operands of ^ have to be constant, as in (2 ^ 3) * x, it binds
+// tighter than a sign and to the right, so -2 ^ 2 is -4 and 2 ^ 3 ^ 2 is
+// 2 ^ 9. Relations are ==, <=, >=, ≤ and ≥. A chain of relations like
+// a <= b <= c stands for a <= b and b <= c, with the strength applying to
+// all of them. If both relations of a chain of three go the same way and
+// its ends are a constant apart, as in 0 <= x <= 100 or x - 5 <= y <= x + 5,
+// the chain is a single range constraint, see Between. Numbers are
+// decimal literals with an optional exponent. Identifiers are resolved
+// with the VariableResolver, or as named constants if it is a
+// ConstantResolver too. An identifier may interpolate constant
+// expressions in braces, as in thumb{i + 1}.top, whose values have to be
+// integers.
+//
+// The calls are Min, Max and Abs, which are only allowed where they don't
+// need integer variables, see Min. Other uses are rejected with an error
+// wrapping NonConvexExpressionErr.
+//
+// Strengths are named, optionally with a weight as in !strong(2.5), or
+// given by their strong:medium:weak components as in !1:0:5. An Auto
+// Layout priority @p below 1000 is the weak strength weighted by p, so
+// priorities compare on a single scale where @500 weighs twice as much as
+// @250, and @1000 is Required.
+type ConstraintParser struct{}
 
 func NewConstraintParser() *ConstraintParser {
-	p, err := regexp.Compile(`\s*(\S*)\s*(==|<=|>=)\s*([^!]*)(?:!(required|strong|medium|weak))?`)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cp := &ConstraintParser{
-		pattern: p,
-	}
-	return cp
+	return &ConstraintParser{}
 }
 
 type VariableResolver interface {
 	ResolveVariable(name string) (*Variable, error)
+}
+
+// ConstantResolver is implemented by resolvers which know named constants.
+// ResolveConstant returns an error for names which aren't constants.
+type ConstantResolver interface {
 	ResolveConstant(name string) (*Expression, error)
 }
 
+// ParseConstraint parses a single constraint. Malformed input is reported
+// as a *ParseError, so is a chain which makes more than one constraint.
 func (cp *ConstraintParser) ParseConstraint(rawConstraint string, variableResolver VariableResolver) (*Constraint, error) {
-	matches := cp.pattern.FindStringSubmatch(rawConstraint)
-	if len(matches) == 5 {
-		variable, err := variableResolver.ResolveVariable(matches[1])
-		if err != nil {
-			return nil, errors.Wrap(err, "can' parse constraint")
+	p, err := newParser(rawConstraint, variableResolver)
+	if err != nil {
+		return nil, err
+	}
+	return p.constraint()
+}
+
+// ParseConstraints parses a constraint which may be a chain of relations,
+// returning the constraints it makes.
+func (cp *ConstraintParser) ParseConstraints(rawConstraint string, variableResolver VariableResolver) ([]*Constraint, error) {
+	p, err := newParser(rawConstraint, variableResolver)
+	if err != nil {
+		return nil, err
+	}
+	return p.constraints()
+}
+
+var strengthNames = map[string]Strength{
+	"required": Required,
+	"strong":   Strong,
+	"medium":   Medium,
+	"weak":     Weak,
+}
+
+// The components of the named strengths, for the weighted form.
+var strengthComponents = map[string][3]float64{
+	"required": {1000, 1000, 1000},
+	"strong":   {1, 0, 0},
+	"medium":   {0, 1, 0},
+	"weak":     {0, 0, 1},
+}
+
+// Nesting deeper than this is rejected rather than risking the stack.
+const maxParseDepth = 1000
+
+// parser is a recursive-descent parser over the tokens of one constraint.
+type parser struct {
+	input    string
+	tokens   []token
+	pos      int
+	depth    int
+	resolver VariableResolver
+	// The tokens of the calls, by the variables standing for them.
+	calls map[*Variable]token
+	// Leave the calls for the solver to check, so a constraint it rejects
+	// can still be read.
+	unchecked bool
+}
+
+func newParser(input string, resolver VariableResolver) (*parser, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{
+		input:    input,
+		tokens:   tokens,
+		resolver: resolver,
+		calls:    map[*Variable]token{},
+	}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// Consume the next token if it is the given punctuation.
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.kind == tokenPunctuation && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) *ParseError {
+	err := newParseError(p.input, t.offset, t.text)
+	err.Message = fmt.Sprintf(format, args...)
+	return err
+}
+
+func (p *parser) expected(t token, expected string) *ParseError {
+	err := p.errorf(t, "expected %s, found %s", expected, t)
+	err.Expected = expected
+	return err
+}
+
+func (p *parser) wrap(t token, cause error, message string) *ParseError {
+	err := p.errorf(t, "%s: %v", message, cause)
+	err.Err = cause
+	return err
+}
+
+func (p *parser) constraint() (*Constraint, error) {
+	start := p.pos
+	cns, err := p.constraints()
+	if err != nil {
+		return nil, err
+	}
+	if len(cns) > 1 {
+		// Point at the relation which starts the second constraint.
+		relations := 0
+		for _, t := range p.tokens[start:] {
+			if t.kind == tokenRelation {
+				if relations++; relations == 2 {
+					return nil, p.errorf(t, "the chain makes %d constraints, use ParseConstraints", len(cns))
+				}
+			}
 		}
-		operator, err := cp.parseOperator(matches[2])
-		if err != nil {
-			return nil, errors.Wrap(err, "can' parse constraint")
+	}
+	return cns[0], nil
+}
+
+// Parse a chain of relations up to the end of input.
+func (p *parser) constraints() ([]*Constraint, error) {
+	first, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*Expression{first}
+	var ops []RelationalOperator
+
+	for len(ops) == 0 || p.peek().kind == tokenRelation {
+		t := p.next()
+		op, exists := relationSpellings[t.text]
+		if t.kind != tokenRelation || !exists {
+			return nil, p.expected(t, "relation")
 		}
-		expression, err := cp.resolveExpression(matches[3], variableResolver)
+		e, err := p.expression()
 		if err != nil {
-			return nil, errors.Wrap(err, "can' parse constraint")
+			return nil, err
 		}
+		ops = append(ops, op)
+		operands = append(operands, e)
+	}
 
-		strength := cp.parseStrength(matches[4])
+	strength, err := p.strength()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
 
-		e2 := variable.SubtractExpression(expression)
-		return NewConstraint(e2, operator, strength), nil
+	if len(ops) == 2 && ops[0] == ops[1] && ops[0] != OP_EQ {
+		lo, hi := operands[0], operands[2]
+		if ops[0] == OP_GE {
+			lo, hi = hi, lo
+		}
+		if span := hi.Subtract(lo); isConstant(span) {
+			cns := []*Constraint{newRange(operands[1].Subtract(lo), span.Constant, strength)}
+			return cns, p.checkCalls(cns)
+		}
 	}
 
-	return nil, fmt.Errorf("could not parse '%s'.", rawConstraint)
+	cns := make([]*Constraint, len(ops))
+	for i, op := range ops {
+		cns[i] = NewConstraint(operands[i].Subtract(operands[i+1]), op, strength)
+	}
+	return cns, p.checkCalls(cns)
 }
 
-func (cp *ConstraintParser) parseOperator(rawOperator string) (RelationalOperator, error) {
-	switch rawOperator {
-	case "EQ", "==":
-		return OP_EQ, nil
-	case "GEQ", ">=":
-		return OP_GE, nil
-	case "LEQ", "<=":
-		return OP_LE, nil
-	default:
-		return OP_EQ, fmt.Errorf("can't parse op string '%s'", rawOperator)
+// Check the constraints only use the calls where they can be lowered.
+func (p *parser) checkCalls(cns []*Constraint) error {
+	if len(p.calls) == 0 || p.unchecked {
+		return nil
 	}
+	for _, c := range cns {
+		if _, v, err := lowerPiecewise(c); err != nil {
+			perr := p.errorf(p.calls[v], "%v", err)
+			perr.Err = err
+			return perr
+		}
+	}
+	return nil
 }
 
-func (cp *ConstraintParser) parseStrength(rawStrength string) Strength {
-	switch rawStrength {
-	case "!strong":
-		return Strong
-	case "!medium":
-		return Medium
-	case "!weak":
-		return Weak
-	default:
-		return Required
+// Check whether the terms of the expression cancel out.
+func isConstant(e *Expression) bool {
+	for _, t := range e.Reduce().Terms {
+		if !FloatNearZero(t.Coefficient) {
+			return false
+		}
 	}
+	return true
 }
 
-func (cp *ConstraintParser) resolveExpression(rawExpression string, variableResolver VariableResolver) (*Expression, error) {
-	tokens := cp.tokenizeExpression(rawExpression)
-	postFixExpression := cp.infixToPostfix(tokens)
-
-	expressionStack := arraystack.New() // *Expression
+// Parse the optional strength at the end of a constraint.
+func (p *parser) strength() (Strength, error) {
+	switch {
+	case p.accept("@"):
+		t := p.peek()
+		priority, err := p.number()
+		if err != nil {
+			return 0, err
+		}
+		if priority <= 0 || priority > 1000 {
+			return 0, p.errorf(t, "priority %s is not in (0, 1000]", t.text)
+		}
+		if priority == 1000 {
+			return Required, nil
+		}
+		return CreateStrength(0, 0, 1, priority), nil
 
-	for _, e := range postFixExpression {
-		switch e {
-		case "+", "-", "*", "/":
-			a, aFound := expressionStack.Pop()
-			b, bFound := expressionStack.Pop()
-			if !aFound || !bFound {
-				return nil, errors.New("can't get expression from stack")
-			}
-			eA, eB := a.(*Expression), b.(*Expression)
-
-			switch e {
-			case "+":
-				expressionStack.Push(eA.Add(eB))
-			case "-":
-				expressionStack.Push(eB.Subtract(eA))
-			case "/":
-				e, err := eB.Divide(eA)
-				if err != nil {
-					return nil, errors.Wrap(err, "can't divide expression")
+	case p.accept("!"):
+		t := p.peek()
+		if t.kind == tokenNumber {
+			var components [3]float64
+			for i := range components {
+				if i > 0 && !p.accept(":") {
+					return 0, p.expected(p.peek(), "':'")
 				}
-				expressionStack.Push(e)
-			case "*":
-				e, err := eA.Multiply(eB)
+				t := p.peek()
+				c, err := p.number()
 				if err != nil {
-					return nil, errors.Wrap(err, "can't multiply expression")
+					return 0, err
 				}
-				expressionStack.Push(e)
-			}
-		default:
-			linearExpression, err := variableResolver.ResolveConstant(e)
-			if err != nil {
-				v, err := variableResolver.ResolveVariable(e)
-				if err != nil {
-					return nil, errors.Wrap(err, "can't resolve variable")
+				if c > 1000 {
+					return 0, p.errorf(t, "strength component %s is above 1000", t.text)
 				}
-				t := NewTermFrom(v)
-				e := NewExpressionFrom(t)
-				linearExpression = e
+				components[i] = c
 			}
-			expressionStack.Push(linearExpression)
+			return CreateStrengthWithDefaultWeight(components[0], components[1], components[2]), nil
 		}
+
+		p.next()
+		strength, exists := strengthNames[t.text]
+		if t.kind != tokenIdentifier || !exists {
+			return 0, p.expected(t, "strength")
+		}
+		if !p.accept("(") {
+			return strength, nil
+		}
+		weight, err := p.number()
+		if err != nil {
+			return 0, err
+		}
+		if closing := p.next(); closing.kind != tokenPunctuation || closing.text != ")" {
+			return 0, p.expected(closing, "')'")
+		}
+		c := strengthComponents[t.text]
+		return CreateStrength(c[0], c[1], c[2], weight), nil
 	}
+	return Required, nil
+}
 
-	re, exists := expressionStack.Pop()
-	if !exists {
-		return nil, errors.New("can't find return expression")
+// Parse a number literal.
+func (p *parser) number() (float64, error) {
+	t := p.next()
+	if t.kind != tokenNumber {
+		return 0, p.expected(t, "number")
 	}
-	returnExpression := re.(*Expression)
-	return returnExpression, nil
+	f, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, p.wrap(t, err, "bad number")
+	}
+	return f, nil
 }
 
-func (cp *ConstraintParser) infixToPostfix(tokens []string) []string {
-	const OPS = "-+/*^"
-	s := arraystack.New() //int
+func (p *parser) expression() (*Expression, error) {
+	e, err := p.term()
+	if err != nil {
+		return nil, err
+	}
 
-	postFix := make([]string, 0, len(tokens))
+	for {
+		switch {
+		case p.accept("+"):
+			other, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			e = e.Add(other)
+		case p.accept("-"):
+			other, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			e = e.Subtract(other)
+		default:
+			return e, nil
+		}
+	}
+}
 
-	for _, token := range tokens {
-		c := token[0]
-		idx := strings.Index(OPS, token)
+func (p *parser) term() (*Expression, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
 
+	for {
+		t := p.peek()
 		switch {
-		case idx != -1 && len(token) == 1:
-			if s.Empty() {
-				s.Push(idx)
-			} else {
-				for !s.Empty() {
-					pi, _ := s.Peek()
-					prec2 := pi.(int) / 2
-					prec1 := idx / 2
-					if prec2 > prec1 || (prec2 == prec1 && token != "^") {
-						x, _ := s.Pop()
-						i := x.(int)
-						op := string(OPS[i])
-						postFix = append(
-							postFix,
-							op,
-						)
-					} else {
-						break
-					}
-				}
-				s.Push(idx)
+		case p.accept("*"):
+			other, err := p.unary()
+			if err != nil {
+				return nil, err
 			}
-		case c == '(':
-			s.Push(-2)
-		case c == ')':
-			for {
-				x, _ := s.Peek()
-				i := x.(int)
-				if i == -2 {
-					break
-				}
-				s.Pop()
-				op := string(OPS[i])
-				postFix = append(postFix, op)
+			switch {
+			case e.IsConstant():
+				e = other.MultiplyFloat(e.Constant)
+			case other.IsConstant():
+				e = e.MultiplyFloat(other.Constant)
+			default:
+				return nil, p.nonLinear(t)
+			}
+		case p.accept("/"):
+			other, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			if !other.IsConstant() {
+				return nil, p.nonLinear(t)
+			}
+			if other.Constant == 0 {
+				return nil, p.errorf(t, "division by zero")
 			}
-			s.Pop()
+			e = e.DivideFloat(other.Constant)
 		default:
-			postFix = append(postFix, token)
+			return e, nil
+		}
+	}
+}
+
+func (p *parser) nonLinear(t token) *ParseError {
+	err := p.errorf(t, "%v", NonLinearExpressionErr)
+	err.Err = NonLinearExpressionErr
+	return err
+}
+
+func (p *parser) unary() (*Expression, error) {
+	if p.depth++; p.depth > maxParseDepth {
+		return nil, p.errorf(p.peek(), "expression nested too deeply")
+	}
+	defer func() { p.depth-- }()
+
+	switch {
+	case p.accept("-"):
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
 		}
+		return e.Negate(), nil
+	case p.accept("+"):
+		return p.unary()
 	}
-	for !s.Empty() {
-		x, _ := s.Pop()
-		i := x.(int)
-		op := string(OPS[i])
-		postFix = append(
-			postFix,
-			op,
-		)
+	return p.power()
+}
+
+func (p *parser) power() (*Expression, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
 	}
-	return postFix
+
+	t := p.peek()
+	if !p.accept("^") {
+		return base, nil
+	}
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if !isConstant(base) || !isConstant(exponent) {
+		return nil, p.nonLinear(t)
+	}
+	value := math.Pow(base.Constant, exponent.Constant)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, p.errorf(t, "%g ^ %g is not a finite number", base.Constant, exponent.Constant)
+	}
+	return NewExpression(value), nil
 }
 
-func (cp *ConstraintParser) tokenizeExpression(rawExpression string) []string {
-	var sb strings.Builder
-	tokens := []string{}
-
-	for _, c := range []rune(rawExpression) {
-		switch c {
-		case '+', '-', '*', '/', '(', ')':
-			if sb.Len() > 0 {
-				tokens = append(tokens, sb.String())
-				sb.Reset()
-			}
-			tokens = append(tokens, string(c))
-		case ' ':
-			// ignore space
-		default:
-			sb.WriteRune(c)
+func (p *parser) primary() (*Expression, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenNumber:
+		p.pos--
+		f, err := p.number()
+		if err != nil {
+			return nil, err
 		}
+		return NewExpression(f), nil
+
+	case t.kind == tokenIdentifier && p.peek().kind == tokenPunctuation && p.peek().text == "(":
+		if kind, exists := piecewiseKinds[t.text]; exists {
+			return p.call(t, kind)
+		}
+		return p.resolve(t)
+
+	case t.kind == tokenIdentifier:
+		return p.resolve(t)
+
+	case t.kind == tokenPunctuation && t.text == "(":
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenPunctuation || closing.text != ")" {
+			return nil, p.expected(closing, "')'")
+		}
+		return e, nil
+	}
+	return nil, p.expected(t, "expression")
+}
+
+// Parse the arguments of a call of min, max or abs.
+func (p *parser) call(name token, kind piecewiseKind) (*Expression, error) {
+	p.next()
+	var args []*Expression
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if closing := p.next(); closing.kind != tokenPunctuation || closing.text != ")" {
+		return nil, p.expected(closing, "',' or ')'")
+	}
+	if kind == piecewiseAbs && len(args) != 1 {
+		return nil, p.errorf(name, "abs takes one argument, found %d", len(args))
+	}
+
+	e := newPiecewise(kind, args)
+	for _, t := range e.Terms {
+		if t.Variable.definition != nil {
+			p.calls[t.Variable] = name
+		}
+	}
+	return e, nil
+}
+
+// Resolve an identifier, as a named constant if the resolver knows one
+// by that name, or as a variable.
+func (p *parser) resolve(t token) (*Expression, error) {
+	name, err := p.name(t)
+	if err != nil {
+		return nil, err
 	}
-	if sb.Len() > 0 {
-		tokens = append(tokens, sb.String())
+	if cr, ok := p.resolver.(ConstantResolver); ok {
+		if e, err := cr.ResolveConstant(name); err == nil && e != nil {
+			return e, nil
+		}
+	}
+	return p.variable(t, name)
+}
+
+// Resolve a variable by its interpolated name.
+func (p *parser) variable(t token, name string) (*Expression, error) {
+	v, err := p.resolver.ResolveVariable(name)
+	if err != nil {
+		return nil, p.wrap(t, err, "can't resolve variable")
+	}
+	if v == nil {
+		return nil, p.errorf(t, "can't resolve variable '%s'", name)
+	}
+	return NewExpressionFrom(NewTermFrom(v)), nil
+}
+
+// Get the name of an identifier, replacing the interpolations in braces
+// by their values, which have to be constant integers.
+func (p *parser) name(t token) (string, error) {
+	if !strings.ContainsRune(t.text, '{') {
+		return t.text, nil
+	}
+
+	var sb strings.Builder
+	text := t.text
+	for {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			sb.WriteString(text)
+			return sb.String(), nil
+		}
+		closing := open + strings.IndexByte(text[open:], '}')
+		sb.WriteString(text[:open])
+
+		start := t.offset + len(t.text) - len(text) + open
+		tokens, err := lexRange(p.input, start+1, start+closing-open)
+		if err != nil {
+			return "", err
+		}
+		sub := &parser{input: p.input, tokens: tokens, depth: p.depth, resolver: p.resolver, calls: p.calls}
+		if sub.peek().kind == tokenEOF {
+			return "", p.errorf(token{tokenPunctuation, "}", start + closing - open}, "expected expression in '{}'")
+		}
+		value, err := sub.constantExpression()
+		if err != nil {
+			return "", err
+		}
+		if value != math.Trunc(value) {
+			return "", sub.errorf(tokens[0], "interpolated value %g is not an integer", value)
+		}
+		sb.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		text = text[closing+1:]
 	}
-	return tokens
 }
@@ -0,0 +1,195 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Compare the constraints by operator, strength and reduced expression.
+// The builder API writes some equalities as rhs - lhs, so an equality
+// also matches its negation.
+func assertSameConstraint(t *testing.T, expected, actual *Constraint, msgAndArgs ...interface{}) {
+	if !assert.NotNil(t, actual, msgAndArgs...) {
+		return
+	}
+	assert.Equal(t, expected.Op, actual.Op, msgAndArgs...)
+	assert.Equal(t, expected.Strength, actual.Strength, msgAndArgs...)
+
+	e := expected.expression
+	if expected.Op == OP_EQ && sameExpression(e.Negate(), actual.expression) {
+		e = e.Negate()
+	}
+	assert.True(t, sameExpression(e, actual.expression), append(msgAndArgs, e, actual.expression)...)
+}
+
+func sameExpression(a, b *Expression) bool {
+	coefficients := func(e *Expression) map[*Variable]float64 {
+		m := map[*Variable]float64{}
+		for _, term := range e.Reduce().Terms {
+			if !FloatNearZero(term.Coefficient) {
+				m[term.Variable] = term.Coefficient
+			}
+		}
+		return m
+	}
+	ca, cb := coefficients(a), coefficients(b)
+	if len(ca) != len(cb) || !FloatEquals(a.Constant, b.Constant) {
+		return false
+	}
+	for v, coefficient := range ca {
+		if !FloatEquals(coefficient, cb[v]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseConstraintExpressions(t *testing.T) {
+	vr := MapResolver{}
+	v := func(name string) *Variable {
+		variable, _ := vr.ResolveVariable(name)
+		return variable
+	}
+	aWidth, bRight, cLeft := v("a.width"), v("b.right"), v("c.left")
+	x, y, z := v("x"), v("y"), v("z")
+
+	for raw, expected := range map[string]*Constraint{
+		"2*a.width + 10 <= b.right - c.left": aWidth.Multiply(2).AddFloat(10).
+			LessThanOrEqualTo(bRight.Subtract(cLeft)),
+		"x == (y + 3) * 2":          x.EqualsExpression(y.AddFloat(3).MultiplyFloat(2)),
+		"2 * (y + 3) == x":          y.AddFloat(3).MultiplyFloat(2).EqualsVariable(x),
+		"-x >= -(y - 1)":            NewExpressionFrom(x.Negate()).GreaterThanOrEqualTo(y.Negate().AddFloat(1)),
+		"x ≤ 1.5e2":                 x.LessThanOrEqualToFloat(150),
+		"x ≥ .5":                    x.GreaterThanOrEqualToFloat(0.5),
+		"x==y/4":                    x.EqualsTerm(*y.Divide(4)),
+		"3 * (x - y) == 2 * z - -1": x.Subtract(y).MultiplyFloat(3).Equals(z.Multiply(2).AddFloat(1)),
+		"x == 2.5E-1 * +y":          x.EqualsTerm(*y.Multiply(0.25)),
+		"x == 10 !weak":             x.EqualsFloat(10).NewModifyStrength(Weak),
+	} {
+		c, err := NewConstraintParser().ParseConstraint(raw, vr)
+		assert.NoError(t, err, raw)
+		assertSameConstraint(t, expected, c, raw)
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	vr := MapResolver{}
+	for _, raw := range []string{
+		"",
+		"x",
+		"x ==",
+		"x == y)",
+		"x == (y",
+		"x y == 1",
+		"x == 1 == 2",
+		"x = 1",
+		"x == 1 / 0",
+		"x == 1 !",
+		"x == 1 $",
+	} {
+		_, err := NewConstraintParser().ParseConstraint(raw, vr)
+		assert.Error(t, err, raw)
+	}
+
+	for _, raw := range []string{"x == y * z", "x == 2 / y", "x * (y + 1) >= 0"} {
+		_, err := NewConstraintParser().ParseConstraint(raw, vr)
+		assert.Equal(t, NonLinearExpressionErr, underlyingCause(err), raw)
+	}
+}
+
+func TestParseStrengths(t *testing.T) {
+	vr := MapResolver{}
+	for raw, expected := range map[string]Strength{
+		"x == 1":              Required,
+		"x == 1 !required":    Required,
+		"x == 1 !strong":      Strong,
+		"x == 1 !medium":      Medium,
+		"x == 1 !weak":        Weak,
+		"x == 1 !strong(2.5)": CreateStrength(1, 0, 0, 2.5),
+		"x == 1 !weak(0.5)":   CreateStrength(0, 0, 1, 0.5),
+		"x == 1 !1:0:5":       CreateStrengthWithDefaultWeight(1, 0, 5),
+		"x == 1 !0:2.5:1000":  CreateStrengthWithDefaultWeight(0, 2.5, 1000),
+		"x == 1 @750":         CreateStrength(0, 0, 1, 750),
+		"x == 1 @999":         Strength(999),
+		"x == 1 @1000":        Required,
+		"x == 1 @0.5":         CreateStrength(0, 0, 0.5, 1),
+	} {
+		c, err := NewConstraintParser().ParseConstraint(raw, vr)
+		if assert.NoError(t, err, raw) {
+			assert.Equal(t, expected, c.Strength, raw)
+		}
+	}
+
+	// Priorities are ordered like their numbers, weigh against each other
+	// in proportion, and all of them give way to the named strengths but
+	// weak.
+	solver := NewSolver()
+	for _, raw := range []string{"x == 10 @250", "x == 20 @300", "y == 10 @500", "y == 20 @300", "y == 20 @300", "z == 10 @999", "z == 20 !medium"} {
+		c, err := NewConstraintParser().ParseConstraint(raw, vr)
+		if assert.NoError(t, err, raw) {
+			assert.NoError(t, solver.AddConstraint(c), raw)
+		}
+	}
+	solver.UpdateVariables()
+	assert.InDelta(t, 20, vr["x"].Value, Epsilon)
+	assert.InDelta(t, 20, vr["y"].Value, Epsilon)
+	assert.InDelta(t, 20, vr["z"].Value, Epsilon)
+
+	for raw, expected := range map[string]string{
+		"x == 1 !strongest":   "strength",
+		"x == 1 !Strong":      "strength",
+		"x == 1 !strong(":     "number",
+		"x == 1 !strong(2":    "')'",
+		"x == 1 !strong(2) !": "end of input",
+		"x == 1 !1:0":         "':'",
+		"x == 1 !1:0:":        "number",
+		"x == 1 @":            "number",
+		"x == 1 @strong":      "number",
+		"x == 1 !2000:0:0":    "",
+		"x == 1 @0":           "",
+		"x == 1 @1001":        "",
+	} {
+		_, err := NewConstraintParser().ParseConstraint(raw, vr)
+		pe, ok := err.(*ParseError)
+		if assert.True(t, ok, raw) {
+			assert.Equal(t, expected, pe.Expected, raw)
+		}
+	}
+}
+
+func TestParsePowers(t *testing.T) {
+	vr := MapResolver{}
+	x, _ := vr.ResolveVariable("x")
+	for raw, expected := range map[string]*Constraint{
+		"x == (2 ^ 3) * 4":      x.EqualsFloat(32),
+		"x == 2 ^ 3 ^ 2":        x.EqualsFloat(512),
+		"x == -2 ^ 2":           x.EqualsFloat(-4),
+		"x == (-2) ^ 2":         x.EqualsFloat(4),
+		"x == 2 ^ -1":           x.EqualsFloat(0.5),
+		"x == 1.5 ^ (1 + 1)":    x.EqualsFloat(2.25),
+		"x >= 8 * 1.25 ^ 2 * 2": x.GreaterThanOrEqualToFloat(25),
+	} {
+		c, err := NewConstraintParser().ParseConstraint(raw, vr)
+		assert.NoError(t, err, raw)
+		assertSameConstraint(t, expected, c, raw)
+	}
+
+	for raw, column := range map[string]int{
+		"x ^ 2 == 1":       3,
+		"x == 2 ^ x":       8,
+		"y == (x + 1) ^ 1": 14,
+	} {
+		_, err := NewConstraintParser().ParseConstraint(raw, vr)
+		assert.Equal(t, NonLinearExpressionErr, underlyingCause(err), raw)
+		if pe, ok := err.(*ParseError); assert.True(t, ok, raw) {
+			assert.Equal(t, column, pe.Column, raw)
+		}
+	}
+
+	for _, raw := range []string{"x == 0 ^ -1", "x == (-8) ^ 0.5", "x == 10 ^ 400", "x == 2 ^", "x == 2 ^ ^ 2"} {
+		_, err := NewConstraintParser().ParseConstraint(raw, vr)
+		_, ok := err.(*ParseError)
+		assert.True(t, ok, raw)
+	}
+}
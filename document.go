@@ -0,0 +1,323 @@
+package cassgowary
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Document is a list of constraints read by ParseDocument.
+type Document struct {
+	Constraints []*DocumentConstraint
+	// Constants are the values declared with let.
+	Constants map[string]float64
+	// Variables are the variables declared with var, in order, with
+	// their initial values.
+	Variables []*Variable
+	Edits     []*DocumentEdit
+}
+
+// DocumentConstraint is a constraint with the line it was read from.
+type DocumentConstraint struct {
+	Constraint *Constraint
+	Line       int
+	Source     string
+}
+
+// DocumentEdit is an edit statement of a document.
+type DocumentEdit struct {
+	Variable *Variable
+	Strength Strength
+	Line     int
+}
+
+// ParseDocument reads a document of constraints, one statement a line:
+//
+//	# comments start with # or //
+//	let gutter = 15          // a named constant
+//	var width = 300          // a variable with its initial value
+//	edit width !strong       // an edit variable, strong by default
+//	title.left == thumb.right + gutter
+//
+// Blank lines are skipped. A line with a chain of relations adds all the
+// constraints of the chain. Names are resolved with the resolver, except
+// for the constants declared with let. Errors are reported as a
+// *ParseError with the line and column in the document.
+//
+// Repeated statements can be written as templates and loops, with blocks
+// opened by a '{' at the end of a line and closed by a line with a '}':
+//
+//	template card(i) {
+//		thumb{i}.top == title{i - 2}.bottom + gutter
+//	}
+//	for i in 2..5 {
+//		card(i)
+//	}
+//
+// A template is expanded where it is called, with its parameters bound to
+// the values of the arguments, a loop runs its body for each integer of
+// the range, both ends included. The parameters and the loop variables
+// are constants in the block, interpolated in names as in thumb{i}.top.
+// Templates are declared at the top level, and their bodies see only
+// their parameters and the constants of the document, not the loop
+// variables of the caller.
+// Errors in an expansion are reported at the line of the template or loop
+// body, with the expansion in the message.
+func (cp *ConstraintParser) ParseDocument(r io.Reader, variableResolver VariableResolver) (*Document, error) {
+	d := &Document{
+		Constants: map[string]float64{},
+	}
+	x := &expansion{
+		document: d,
+		scope: &documentScope{
+			constants: d.Constants,
+			bindings:  map[string]float64{},
+			resolver:  variableResolver,
+		},
+		templates: map[string]*template{},
+	}
+
+	var lines []sourceLine
+	reader := bufio.NewReader(r)
+	for line, offset := 1, 0; ; line++ {
+		raw, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrapf(err, "can't read line %d", line)
+		}
+		lines = append(lines, sourceLine{
+			text:   strings.TrimRight(raw, "\r\n"),
+			line:   line,
+			offset: offset,
+		})
+		offset += len(raw)
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err := x.run(lines); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// A line of a document, with its number and the offset of its start.
+type sourceLine struct {
+	text   string
+	line   int
+	offset int
+}
+
+// Get a parser over the line without its comment, or nil for a blank line.
+func (l sourceLine) parser(resolver VariableResolver) (*parser, error) {
+	text := stripComment(l.text)
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	return newParser(text, resolver)
+}
+
+// Parse a statement which isn't a block.
+func (x *expansion) statement(p *parser, l sourceLine) error {
+	d := x.document
+	if first, second := p.tokens[0], p.tokens[1]; first.kind == tokenIdentifier && second.kind == tokenIdentifier {
+		switch first.text {
+		case "let":
+			p.next()
+			return p.letStatement(d.Constants)
+		case "var":
+			p.next()
+			v, err := p.varStatement()
+			if err == nil {
+				d.Variables = append(d.Variables, v)
+			}
+			return err
+		case "edit":
+			p.next()
+			edit, err := p.editStatement()
+			if err == nil {
+				edit.Line = l.line
+				d.Edits = append(d.Edits, edit)
+			}
+			return err
+		case "template", "for":
+			return p.expected(p.tokens[len(p.tokens)-1], "'{' at the end of the line")
+		}
+	}
+
+	cns, err := p.constraints()
+	if err != nil {
+		return err
+	}
+	source := strings.TrimSpace(stripComment(l.text))
+	for _, c := range cns {
+		d.Constraints = append(d.Constraints, &DocumentConstraint{
+			Constraint: c,
+			Line:       l.line,
+			Source:     source,
+		})
+	}
+	return nil
+}
+
+// Cut the comment off the line.
+func stripComment(text string) string {
+	if i := strings.Index(text, "#"); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.Index(text, "//"); i >= 0 {
+		text = text[:i]
+	}
+	return text
+}
+
+// AddTo adds the constraints to the solver, followed by the edit
+// variables, which are suggested the current values of their variables.
+func (d *Document) AddTo(s *Solver) error {
+	for _, dc := range d.Constraints {
+		if err := s.AddConstraint(dc.Constraint); err != nil {
+			return errors.Wrapf(err, "line %d", dc.Line)
+		}
+	}
+	for _, edit := range d.Edits {
+		if err := s.AddEditVariable(edit.Variable, edit.Strength); err != nil {
+			return errors.Wrapf(err, "line %d", edit.Line)
+		}
+		if err := s.SuggestValue(edit.Variable, edit.Variable.Value); err != nil {
+			return errors.Wrapf(err, "line %d", edit.Line)
+		}
+	}
+	return nil
+}
+
+// The names of a document: its constants and whatever the resolver knows.
+type documentScope struct {
+	constants map[string]float64
+	// The parameters and loop variables of the blocks being expanded.
+	bindings map[string]float64
+	resolver VariableResolver
+}
+
+func (s *documentScope) ResolveVariable(name string) (*Variable, error) {
+	return s.resolver.ResolveVariable(name)
+}
+
+func (s *documentScope) ResolveConstant(name string) (*Expression, error) {
+	if value, exists := s.bindings[name]; exists {
+		return NewExpression(value), nil
+	}
+	if value, exists := s.constants[name]; exists {
+		return NewExpression(value), nil
+	}
+	if cr, ok := s.resolver.(ConstantResolver); ok {
+		return cr.ResolveConstant(name)
+	}
+	return nil, errors.Errorf("unknown constant '%s'", name)
+}
+
+// let name = expression
+func (p *parser) letStatement(constants map[string]float64) error {
+	t := p.next()
+	name, err := p.name(t)
+	if err != nil {
+		return err
+	}
+	if _, exists := constants[name]; exists {
+		return p.errorf(t, "constant '%s' is already declared", name)
+	}
+	if !p.accept("=") {
+		return p.expected(p.peek(), "'='")
+	}
+	value, err := p.constantExpression()
+	if err != nil {
+		return err
+	}
+	constants[name] = value
+	return nil
+}
+
+// var name [= expression]
+func (p *parser) varStatement() (*Variable, error) {
+	v, err := p.declared()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("=") {
+		value, err := p.constantExpression()
+		if err != nil {
+			return nil, err
+		}
+		v.Value = value
+		return v, nil
+	}
+	return v, p.end()
+}
+
+// edit name [strength]
+func (p *parser) editStatement() (*DocumentEdit, error) {
+	v, err := p.declared()
+	if err != nil {
+		return nil, err
+	}
+
+	strength := Strong
+	if t := p.peek(); t.kind != tokenEOF {
+		if t.kind != tokenPunctuation || t.text != "!" && t.text != "@" {
+			return nil, p.expected(t, "strength")
+		}
+		if strength, err = p.strength(); err != nil {
+			return nil, err
+		}
+		if strength >= Required {
+			return nil, p.errorf(t, "edit variables can't be required")
+		}
+	}
+	return &DocumentEdit{Variable: v, Strength: strength}, p.end()
+}
+
+// Resolve the variable named by the next token of a var or edit statement.
+func (p *parser) declared() (*Variable, error) {
+	t := p.next()
+	name, err := p.name(t)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.variable(t, name)
+	if err != nil {
+		return nil, err
+	}
+	return e.Terms[0].Variable, nil
+}
+
+// Parse an expression which has to be constant, up to the end of input.
+func (p *parser) constantExpression() (float64, error) {
+	value, err := p.constant()
+	if err != nil {
+		return 0, err
+	}
+	return value, p.end()
+}
+
+// Parse an expression which has to be constant.
+func (p *parser) constant() (float64, error) {
+	t := p.peek()
+	e, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	if !e.IsConstant() {
+		return 0, p.errorf(t, "expected constant expression")
+	}
+	return e.Constant, nil
+}
+
+// Expect the end of input.
+func (p *parser) end() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return p.expected(t, "end of input")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cassgowary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gridDocument = `# The grid of real_world_test.go, with the container as an edit.
+let rowPadding = 15
+let thumbRatio = 1 / 2
+
+var container.width = 300
+edit container.width !strong
+
+container.columnWidth == container.width * 0.4
+container.thumbHeight == container.columnWidth * thumbRatio   // half as high
+container.padding == container.width * (0.2 / 3)
+container.leftPadding == container.padding
+container.rightPadding == container.width - container.padding
+container.paddingUnderThumb == 5
+container.rowPadding == rowPadding
+
+thumb0.left == container.leftPadding
+thumb0.top == container.padding
+thumb0.height == container.thumbHeight
+thumb0.width == container.columnWidth
+
+title0.left == container.leftPadding
+title0.top == thumb0.bottom + container.paddingUnderThumb
+title0.height == 100
+title0.width == container.columnWidth
+
+thumb1.right == container.rightPadding
+thumb1.top == container.padding
+thumb1.height == container.thumbHeight
+thumb1.width == container.columnWidth
+
+title1.right == container.rightPadding
+title1.top == thumb0.bottom + container.paddingUnderThumb
+title1.height == 110
+title1.width == container.columnWidth
+
+thumb2.left == container.leftPadding
+thumb2.top >= title0.bottom + container.rowPadding
+thumb2.top == title0.bottom + container.rowPadding !weak
+thumb2.top >= title1.bottom + container.rowPadding
+thumb2.top == title1.bottom + container.rowPadding !weak
+`
+
+func TestParseDocument(t *testing.T) {
+	solver := NewSolver()
+	vr := NewNodeResolver(solver)
+	d, err := NewConstraintParser().ParseDocument(strings.NewReader(gridDocument), vr)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]float64{"rowPadding": 15, "thumbRatio": 0.5}, d.Constants)
+	assert.Len(t, d.Variables, 1)
+	assert.Equal(t, 300.0, d.Variables[0].Value)
+	if assert.Len(t, d.Edits, 1) {
+		assert.Equal(t, vr.Nodes["container"]["width"], d.Edits[0].Variable)
+		assert.Equal(t, Strong, d.Edits[0].Strength)
+		assert.Equal(t, 6, d.Edits[0].Line)
+	}
+	assert.Len(t, d.Constraints, 28)
+	assert.Equal(t, 9, d.Constraints[1].Line)
+	assert.Equal(t, "container.thumbHeight == container.columnWidth * thumbRatio", d.Constraints[1].Source)
+	assert.Equal(t, Weak, d.Constraints[27].Constraint.Strength)
+
+	assert.NoError(t, d.AddTo(solver))
+	solver.UpdateVariables()
+	assert.InDelta(t, 20, vr.Nodes["thumb0"]["top"].Value, Epsilon)
+	assert.InDelta(t, 85, vr.Nodes["title1"]["top"].Value, Epsilon)
+	assert.InDelta(t, 210, vr.Nodes["thumb2"]["top"].Value, Epsilon)
+
+	assert.NoError(t, solver.SuggestValue(vr.Nodes["container"]["width"], 600))
+	solver.UpdateVariables()
+	assert.InDelta(t, 40, vr.Nodes["thumb0"]["top"].Value, Epsilon)
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	for _, tc := range []struct {
+		document     string
+		line, column int
+		offset       int
+		message      string
+	}{
+		{"x == 1\n\n  y == (2\n", 3, 10, 17, "expected ')', found end of input"},
+		{"x == 1\r\ny == 2 +\r\n", 2, 9, 16, "expected expression, found end of input"},
+		{"let a = 1\nlet a = 2", 2, 5, 14, "constant 'a' is already declared"},
+		{"let a = x", 1, 9, 8, "expected constant expression"},
+		{"let a 1", 1, 7, 6, "expected '=', found '1'"},
+		{"var x = 1 2", 1, 11, 10, "expected end of input, found '2'"},
+		{"edit x !required", 1, 8, 7, "edit variables can't be required"},
+		{"edit x strong", 1, 8, 7, "expected strength, found 'strong'"},
+		{"# fine\nx == 1 // fine\nx = 1 # not fine", 3, 3, 24, "expected relation, found '='"},
+	} {
+		_, err := NewConstraintParser().ParseDocument(strings.NewReader(tc.document), MapResolver{})
+		pe, ok := err.(*ParseError)
+		if !assert.True(t, ok, tc.document) {
+			continue
+		}
+		assert.Equal(t, tc.line, pe.Line, tc.document)
+		assert.Equal(t, tc.column, pe.Column, tc.document)
+		assert.Equal(t, tc.offset, pe.Offset, tc.document)
+		assert.Equal(t, tc.message, pe.Message, tc.document)
+	}
+}
+
+func TestParseDocumentKeywordsAsNames(t *testing.T) {
+	vr := MapResolver{}
+	d, err := NewConstraintParser().ParseDocument(strings.NewReader("edit == let + 1\nvar >= 0"), vr)
+	assert.NoError(t, err)
+	assert.Len(t, d.Constraints, 2)
+	assert.Empty(t, d.Edits)
+	assert.Len(t, vr, 3)
+}
+
+func TestParseDocumentChains(t *testing.T) {
+	d, err := NewConstraintParser().ParseDocument(strings.NewReader("0 <= x <= 100\na <= b <= c !weak"), MapResolver{})
+	assert.NoError(t, err)
+	if assert.Len(t, d.Constraints, 3) {
+		assert.Equal(t, OP_RANGE, d.Constraints[0].Constraint.Op)
+		assert.Equal(t, 1, d.Constraints[0].Line)
+		for _, dc := range d.Constraints[1:] {
+			assert.Equal(t, 2, dc.Line)
+			assert.Equal(t, "a <= b <= c !weak", dc.Source)
+		}
+	}
+}
@@ -1,23 +1,49 @@
 package cassgowary
 
 import (
-	"errors"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
+const unsatisfiableReason = "unsatisfiable constraint"
+
 var (
-	DuplicateConstraintErr     = constraintError("unsatisfiable constraint")
+	DuplicateConstraintErr     = constraintError("duplicate constraint")
 	DuplicateEditVariableErr   = errors.New("duplicate edit variable")
 	InternalSolverErr          = errors.New("internal solver error")
+	NonConvexExpressionErr     = errors.New("non-convex expression")
 	NonLinearExpressionErr     = errors.New("non-linear expression")
 	RequiredFailureErr         = errors.New("required failure")
 	UnknownConstraintErr       = constraintError("unknown constraint")
 	UnknownEditVariableErr     = errors.New("unknown edit variable")
-	UnsatisfiableConstraintErr = constraintError("unsatisfiable constraint")
+	UnknownVariableErr         = errors.New("unknown variable")
+	UnsatisfiableConstraintErr = constraintError(unsatisfiableReason)
 )
 
+// ConstraintError is the error returned for a problem with a
+// single constraint.
+type ConstraintError struct {
+	Reason     string
+	Constraint *Constraint
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s %s", e.Reason, e.Constraint)
+}
+
 func constraintError(prefix string) func(c *Constraint) error {
 	return func(c *Constraint) error {
-		return fmt.Errorf("%s %s", prefix, c)
+		return &ConstraintError{
+			Reason:     prefix,
+			Constraint: c,
+		}
 	}
 }
+
+// IsUnsatisfiable reports whether the error was caused by a constraint
+// that conflicts with the required constraints already in the solver.
+func IsUnsatisfiable(err error) bool {
+	ce, ok := errors.Cause(err).(*ConstraintError)
+	return ok && ce.Reason == unsatisfiableReason
+}
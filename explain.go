@@ -0,0 +1,231 @@
+package cassgowary
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+)
+
+// Explanation describes why a variable has its current value. The value
+// is the constant plus the terms, each of which is a parametric symbol
+// of the tableau at zero: a free variable, or the slack, error or dummy
+// symbol of a constraint.
+type Explanation struct {
+	Variable *Variable
+	Value    float64
+	Constant float64
+	Terms    []ExplanationTerm
+
+	// Tight are the constraints in the terms, which hold with equality,
+	// plus the aliases the variable was resolved through.
+	Tight []*Constraint
+	// Nonzero are the slack and error symbols of the variable's component
+	// which are not at zero.
+	Nonzero []SymbolValue
+	// Won and Lost are the non required constraints of the component,
+	// split by whether they are satisfied.
+	Won, Lost []*Constraint
+
+	edits map[*Constraint]string
+}
+
+// ExplanationTerm is a parametric symbol and the rate the variable moves
+// with it. Either Variable or Constraint is set. Kind is the kind of the
+// symbol: variable, slack, error or dummy. The symbol of a constraint is
+// measured in the units of its expression.
+type ExplanationTerm struct {
+	Coefficient float64
+	Symbol      string
+	Kind        string
+	Variable    *Variable
+	Constraint  *Constraint
+}
+
+// SymbolValue is the value of a slack or error symbol of a constraint, in
+// the units of its expression.
+type SymbolValue struct {
+	Symbol     string
+	Kind       string
+	Constraint *Constraint
+	Value      float64
+}
+
+// Explain writes the current row of the variable in terms of the
+// constraints and edit variables it depends on.
+func (s *Solver) Explain(v *Variable) *Explanation {
+	x := &Explanation{
+		Variable: v,
+		edits:    map[*Constraint]string{},
+	}
+
+	target, offset := s.resolveVariable(v)
+	for u := v; u != target; u = s.aliases[u].target {
+		x.Tight = append(x.Tight, s.aliases[u].constraint)
+	}
+	x.Constant = offset
+	x.Value = offset
+	if target == nil {
+		return x
+	}
+
+	owner, exists := s.owners.Get(target)
+	if !exists {
+		x.Terms = append(x.Terms, ExplanationTerm{
+			Coefficient: 1,
+			Kind:        symbolKindNames[symbolExternal],
+			Variable:    target,
+		})
+		return x
+	}
+	cp := owner.(*component)
+
+	for _, m := range []*linkedhashmap.Map{s.edits, s.stays} {
+		kind := "edit"
+		if m == s.stays {
+			kind = "stay"
+		}
+		m.Each(func(k, e interface{}) {
+			x.edits[e.(*editInfo).constraint] = kind + " " + k.(*Variable).Name
+		})
+	}
+
+	// Find what the symbols of the component stand for.
+	variables := map[*symbol]*Variable{}
+	cp.vars.Each(func(_ int, k interface{}) {
+		if sym, exists := s.vars.Get(k); exists {
+			variables[sym.(*symbol)] = k.(*Variable)
+		}
+	})
+	// The symbols of a scaled row are scaled as well, the scales convert
+	// them back to the units of the constraint.
+	constraints := map[*symbol]*Constraint{}
+	scales := map[*symbol]float64{}
+	cp.cns.Each(func(k, t interface{}) {
+		for _, sym := range t.(*tag).symbols() {
+			constraints[sym] = k.(*Constraint)
+			scales[sym] = t.(*tag).scale
+		}
+	})
+	value := func(sym *symbol) float64 {
+		if r, exists := cp.rows.Get(sym); exists {
+			return r.constant
+		}
+		return 0
+	}
+
+	entry, _ := s.vars.Get(target)
+	sym := entry.(*symbol)
+	r, basic := cp.rows.Get(sym)
+	if !basic {
+		x.Terms = append(x.Terms, ExplanationTerm{
+			Coefficient: 1,
+			Symbol:      sym.String(),
+			Kind:        symbolKindNames[sym.kind],
+			Variable:    target,
+		})
+		return x
+	}
+
+	x.Constant += r.constant
+	x.Value += r.constant
+	tight := map[*Constraint]bool{}
+	r.cells.Each(func(sym *symbol, coefficient float64) {
+		if scale, exists := scales[sym]; exists {
+			coefficient *= scale
+		}
+		term := ExplanationTerm{
+			Coefficient: coefficient,
+			Symbol:      sym.String(),
+			Kind:        symbolKindNames[sym.kind],
+			Variable:    variables[sym],
+			Constraint:  constraints[sym],
+		}
+		x.Terms = append(x.Terms, term)
+		if c := term.Constraint; c != nil && !tight[c] {
+			tight[c] = true
+			x.Tight = append(x.Tight, c)
+		}
+	})
+
+	cp.cns.Each(func(k, t interface{}) {
+		c := k.(*Constraint)
+		lost := false
+		for _, sym := range t.(*tag).symbols() {
+			if sym.kind == symbolDummy {
+				continue
+			}
+			if v := value(sym); math.Abs(v) > Epsilon {
+				x.Nonzero = append(x.Nonzero, SymbolValue{
+					Symbol:     sym.String(),
+					Kind:       symbolKindNames[sym.kind],
+					Constraint: c,
+					Value:      v / t.(*tag).scale,
+				})
+				lost = lost || sym.kind == symbolError
+			}
+		}
+
+		switch {
+		case c.Strength >= Required:
+		case lost:
+			x.Lost = append(x.Lost, c)
+		default:
+			x.Won = append(x.Won, c)
+		}
+	})
+	return x
+}
+
+func (x *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s = %g\n", x.Variable.Name, x.Value)
+	fmt.Fprintf(&sb, "  = %g\n", x.Constant)
+	for _, t := range x.Terms {
+		sign, coefficient := "+", t.Coefficient
+		if coefficient < 0 {
+			sign, coefficient = "-", -coefficient
+		}
+		fmt.Fprintf(&sb, "  %s %g * %s\n", sign, coefficient, x.describeTerm(t))
+	}
+
+	x.writeConstraints(&sb, "tight", x.Tight)
+	if len(x.Nonzero) > 0 {
+		sb.WriteString("nonzero:\n")
+		for _, sv := range x.Nonzero {
+			fmt.Fprintf(&sb, "  %s(%s) = %g\n", sv.Kind, x.describe(sv.Constraint), sv.Value)
+		}
+	}
+	x.writeConstraints(&sb, "won", x.Won)
+	x.writeConstraints(&sb, "lost", x.Lost)
+	return sb.String()
+}
+
+func (x *Explanation) describeTerm(t ExplanationTerm) string {
+	if t.Variable != nil {
+		return t.Variable.Name + " (free)"
+	}
+	return fmt.Sprintf("%s(%s)", t.Kind, x.describe(t.Constraint))
+}
+
+func (x *Explanation) writeConstraints(sb *strings.Builder, title string, cns []*Constraint) {
+	if len(cns) == 0 {
+		return
+	}
+	sb.WriteString(title + ":\n")
+	for _, c := range cns {
+		sb.WriteString("  " + x.describe(c) + "\n")
+	}
+}
+
+// Describe the constraint in one line, naming edits and stays as such.
+func (x *Explanation) describe(c *Constraint) string {
+	if c == nil {
+		return "?"
+	}
+	if edit, exists := x.edits[c]; exists {
+		return fmt.Sprintf("%s %s", edit, formatStrength(c.Strength))
+	}
+	return Format(c)
+}
@@ -0,0 +1,135 @@
+package cassgowary
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainTightAndLost(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	bound := x.GreaterThanOrEqualToFloat(10)
+	wish := x.EqualsFloat(5).NewModifyStrength(Strong)
+	offset := y.EqualsExpression(x.AddFloat(20))
+	for _, c := range []*Constraint{bound, wish, offset} {
+		assert.NoError(t, solver.AddConstraint(c))
+	}
+	solver.UpdateVariables()
+
+	x2 := solver.Explain(y)
+	assert.Equal(t, y, x2.Variable)
+	assert.InDelta(t, 30, x2.Value, Epsilon)
+	assert.InDelta(t, y.Value, x2.Value, Epsilon)
+	assert.Contains(t, x2.Tight, bound)
+	assert.Contains(t, x2.Tight, offset)
+	assert.Equal(t, []*Constraint{wish}, x2.Lost)
+	assert.Empty(t, x2.Won)
+	assert.Len(t, x2.Nonzero, 1)
+	assert.Equal(t, wish, x2.Nonzero[0].Constraint)
+	assert.Equal(t, "error", x2.Nonzero[0].Kind)
+	assert.InDelta(t, 5, x2.Nonzero[0].Value, Epsilon)
+
+	text := x2.String()
+	assert.Contains(t, text, "y = 30\n")
+	assert.Contains(t, text, "+ 1 * slack(x >= 10)\n")
+	assert.Contains(t, text, "lost:\n  x == 5 !strong\n")
+}
+
+func TestExplainScaledRows(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	bound := x.Multiply(2).GreaterThanOrEqualToFloat(20)
+	wish := x.Multiply(4).EqualsFloat(20).NewModifyStrength(Strong)
+	far := x.Multiply(4).GreaterThanOrEqualToFloat(80).NewModifyStrength(Weak)
+	for _, c := range []*Constraint{bound, wish, far} {
+		assert.NoError(t, solver.AddConstraint(c))
+	}
+	solver.UpdateVariables()
+
+	// The violations are in the units of the constraints, not of their
+	// scaled rows.
+	x2 := solver.Explain(x)
+	assert.InDelta(t, 10, x2.Value, Epsilon)
+	violations := map[*Constraint]float64{}
+	for _, sv := range x2.Nonzero {
+		violations[sv.Constraint] += sv.Value
+	}
+	assert.InDelta(t, 20, violations[wish], Epsilon)
+	assert.InDelta(t, 40, violations[far], Epsilon)
+
+	// Moving the slack of bound by one moves x by a half.
+	if assert.Len(t, x2.Terms, 1) {
+		assert.Equal(t, bound, x2.Terms[0].Constraint)
+		assert.InDelta(t, 0.5, x2.Terms[0].Coefficient, Epsilon)
+	}
+}
+
+func TestExplainEdit(t *testing.T) {
+	w := newResizableWindow(t)
+	assert.NoError(t, w.solver.SuggestValue(w.width, 200))
+	w.solver.UpdateVariables()
+
+	x := w.solver.Explain(w.center)
+	assert.InDelta(t, 110, x.Value, Epsilon)
+	assert.Contains(t, x.String(), "(edit width !strong)")
+
+	// The free variable explains itself.
+	free := NewVariable("free")
+	x = w.solver.Explain(free)
+	assert.Equal(t, float64(0), x.Value)
+	assert.Len(t, x.Terms, 1)
+	assert.Equal(t, free, x.Terms[0].Variable)
+	assert.Equal(t, "variable", x.Terms[0].Kind)
+}
+
+func TestExplainAlias(t *testing.T) {
+	solver := NewSolver(WithPresolve())
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	alias := x.EqualsExpression(y.AddFloat(5))
+	assert.NoError(t, solver.AddConstraint(alias))
+	assert.NoError(t, solver.AddConstraint(y.EqualsFloat(10)))
+	solver.UpdateVariables()
+
+	// The alias is taken for y, the first variable of y + 5 - x.
+	explanation := solver.Explain(y)
+	assert.InDelta(t, 10, explanation.Value, Epsilon)
+	assert.Equal(t, alias, explanation.Tight[0])
+
+	fixed := NewVariable("fixed")
+	assert.NoError(t, solver.AddConstraint(fixed.EqualsFloat(3)))
+	explanation = solver.Explain(fixed)
+	assert.Equal(t, float64(3), explanation.Value)
+	assert.Empty(t, explanation.Terms)
+	assert.Len(t, explanation.Tight, 1)
+}
+
+func ExampleSolver_Explain() {
+	solver := NewSolver()
+	left := NewVariable("left")
+	width := NewVariable("width")
+	right := NewVariable("right")
+
+	solver.AddConstraint(left.EqualsFloat(10))
+	solver.AddConstraint(right.EqualsExpression(left.Add(width)))
+	solver.AddConstraint(width.GreaterThanOrEqualToFloat(100))
+	solver.UpdateVariables()
+
+	fmt.Print(solver.Explain(right))
+	// Output:
+	// right = 110
+	//   = 110
+	//   - 1 * dummy(left == 10)
+	//   + 1 * dummy(left - right + width == 0)
+	//   + 1 * slack(width >= 100)
+	// tight:
+	//   left == 10
+	//   left - right + width == 0
+	//   width >= 100
+}
@@ -0,0 +1,201 @@
+package cassgowary
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var operatorSymbols = map[RelationalOperator]string{
+	OP_LE: "<=",
+	OP_GE: ">=",
+	OP_EQ: "==",
+}
+
+// Format writes the constraint in the syntax of the ConstraintParser, so
+// that parsing the result gives an equivalent constraint. The terms are
+// merged and sorted by variable, the constant goes to the right, and the
+// strength is named, with a weight if it has to:
+//
+//	2*x - y >= 10 !strong
+//	10 <= x <= 20
+//	-min(300, 0.4*container.width) + width <= 0 !strong(2)
+//
+// Variable names are written as they are, names which aren't identifiers
+// of the constraint language can't be read back.
+func Format(c *Constraint) string {
+	return nameFormatter.constraint(c)
+}
+
+// FormatExpression writes the expression in the syntax of the
+// ConstraintParser, with the terms ordered like Format does.
+func FormatExpression(e *Expression) string {
+	return nameFormatter.expression(e)
+}
+
+// A formatter writes constraints in the syntax of the ConstraintParser.
+type formatter struct {
+	// Get the name of a variable which isn't a Min, Max or Abs.
+	name func(v *Variable) string
+	// Merge the terms and sort them by variable, rather than writing them
+	// as they are in the expression.
+	sorted bool
+}
+
+// The formatter of Format, writing variables by name.
+var nameFormatter = formatter{
+	name:   func(v *Variable) string { return v.Name },
+	sorted: true,
+}
+
+func (f formatter) constraint(c *Constraint) string {
+	terms := f.terms(c.expression.Terms)
+	if terms == "" {
+		terms = "0"
+	}
+	constant := -c.expression.Constant + 0 // no -0
+
+	var sb strings.Builder
+	if c.Op == OP_RANGE {
+		lo, hi := constant, constant+c.span
+		if hi-lo != c.span {
+			// Moving the constant over would round the span.
+			lo, hi, terms = 0, c.span, f.expression(c.expression)
+		}
+		sb.WriteString(formatNumber(lo))
+		sb.WriteString(" <= ")
+		sb.WriteString(terms)
+		sb.WriteString(" <= ")
+		sb.WriteString(formatNumber(hi))
+	} else {
+		sb.WriteString(terms)
+		sb.WriteString(" " + operatorSymbols[c.Op] + " ")
+		sb.WriteString(formatNumber(constant))
+	}
+	if c.Strength < Required {
+		sb.WriteString(" " + formatStrength(c.Strength))
+	}
+	return sb.String()
+}
+
+func (f formatter) expression(e *Expression) string {
+	terms := f.terms(e.Terms)
+	switch {
+	case terms == "":
+		return formatNumber(e.Constant)
+	case e.Constant < 0:
+		return terms + " - " + formatNumber(-e.Constant)
+	case e.Constant > 0:
+		return terms + " + " + formatNumber(e.Constant)
+	}
+	return terms
+}
+
+// Write the terms. Sorted, they are merged and ordered by variable, or by
+// coefficient for variables of the same name.
+func (f formatter) terms(terms Terms) string {
+	type formatted struct {
+		variable    string
+		coefficient float64
+	}
+	var written []formatted
+	if f.sorted {
+		for _, t := range (&Expression{Terms: terms}).Reduce().Terms {
+			if t.Coefficient != 0 {
+				written = append(written, formatted{f.variable(t.Variable), t.Coefficient})
+			}
+		}
+		sort.SliceStable(written, func(i, j int) bool {
+			if written[i].variable != written[j].variable {
+				return written[i].variable < written[j].variable
+			}
+			return written[i].coefficient < written[j].coefficient
+		})
+	} else {
+		for _, t := range terms {
+			written = append(written, formatted{f.variable(t.Variable), t.Coefficient})
+		}
+	}
+
+	var sb strings.Builder
+	for i, t := range written {
+		coefficient := t.coefficient
+		switch {
+		case i == 0 && coefficient < 0:
+			sb.WriteString("-")
+			coefficient = -coefficient
+		case coefficient < 0:
+			sb.WriteString(" - ")
+			coefficient = -coefficient
+		case i > 0:
+			sb.WriteString(" + ")
+		}
+		if coefficient != 1 {
+			sb.WriteString(formatNumber(coefficient) + "*")
+		}
+		sb.WriteString(t.variable)
+	}
+	return sb.String()
+}
+
+// Write the variable by name, or by its definition for Min, Max and Abs.
+func (f formatter) variable(v *Variable) string {
+	d := v.definition
+	if d == nil {
+		return f.name(v)
+	}
+	args := make([]string, len(d.args))
+	for i, arg := range d.args {
+		args[i] = f.expression(arg)
+	}
+	return piecewiseNames[d.kind] + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Write the shortest decimal which reads back as the same number.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Write the strength by name, with a weight if it has a single component,
+// or else by its strong:medium:weak components.
+func formatStrength(s Strength) string {
+	names := []string{"strong", "medium", "weak"}
+	for _, name := range append([]string{"required"}, names...) {
+		if s == strengthNames[name] {
+			return "!" + name
+		}
+	}
+
+	f := float64(s)
+	var components [3]float64
+	for i, name := range names {
+		unit := float64(strengthNames[name])
+		if i < len(names)-1 {
+			components[i] = math.Min(1000, math.Floor(f/unit))
+		} else {
+			components[i] = f / unit
+		}
+		f -= components[i] * unit
+	}
+
+	single := -1
+	for i, c := range components {
+		switch {
+		case c == 0:
+		case single == -1:
+			single = i
+		default:
+			single = -2
+		}
+	}
+	if single >= 0 {
+		name := names[single]
+		weight := float64(s / strengthNames[name])
+		c := strengthComponents[name]
+		if CreateStrength(c[0], c[1], c[2], weight) == s {
+			return "!" + name + "(" + formatNumber(weight) + ")"
+		}
+	}
+	return "!" + formatNumber(components[0]) + ":" + formatNumber(components[1]) + ":" + formatNumber(components[2])
+}
@@ -0,0 +1,119 @@
+package cassgowary
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	vr := MapResolver{}
+	v := func(name string) *Expression {
+		variable, _ := vr.ResolveVariable(name)
+		return NewExpressionFrom(NewTermFrom(variable))
+	}
+	x, y, width := v("x"), v("y"), v("container.width")
+
+	for expected, c := range map[string]*Constraint{
+		"x - y == 10":              NewConstraintRequired(y.MultiplyFloat(-1).Add(x).AddFloat(-10), OP_EQ),
+		"2*x - y >= 10 !strong":    NewConstraint(y.Negate().Add(x.MultiplyFloat(2)).AddFloat(-10), OP_GE, Strong),
+		"-x <= -0.5 !weak":         NewConstraint(x.Negate().AddFloat(0.5), OP_LE, Weak),
+		"x == 0":                   NewConstraintRequired(x.Add(y).Subtract(y), OP_EQ),
+		"0 == 5":                   NewConstraintRequired(NewExpression(-5), OP_EQ),
+		"10 <= x <= 20":            Between(10, x, 20),
+		"-5 <= x - y <= 5 !medium": Between(-5, x.Subtract(y), 5).NewModifyStrength(Medium),
+		"0 <= x + 1e+20 <= 0.5":    newRange(x.AddFloat(1e20), 0.5, Required),
+		"1e-07*x + 1e+21*y <= 0":   NewConstraintRequired(y.MultiplyFloat(1e21).Add(x.MultiplyFloat(1e-7)), OP_LE),
+		"x <= 0 !2:500:0":          NewConstraint(x, OP_LE, CreateStrength(1, 0, 0, 2.5)),
+		"x <= 0 !weak(0.5)":        NewConstraint(x, OP_LE, CreateStrength(0, 0, 1, 0.5)),
+		"x <= 0 !strong(999)":      NewConstraint(x, OP_LE, AlmostRequired),
+		"x <= 0 !medium(2)":        NewConstraint(x, OP_LE, CreateStrengthWithDefaultWeight(0, 2, 0)),
+		"x <= 0 !1:0:5":            NewConstraint(x, OP_LE, CreateStrengthWithDefaultWeight(1, 0, 5)),
+		"-min(300, 0.4*container.width) + x <= 0 !strong(2)": NewConstraint(
+			x.Subtract(Min(NewExpression(300), width.MultiplyFloat(0.4))), OP_LE, CreateStrength(1, 0, 0, 2)),
+		"-min(300, 0.4*container.width) + x <= 0": NewConstraintRequired(
+			x.Subtract(Min(NewExpression(300), width.MultiplyFloat(0.4))), OP_LE),
+		"-2*abs(x - y + 1) + y >= 0": NewConstraintRequired(
+			y.Subtract(Abs(x.Subtract(y).AddFloat(1)).MultiplyFloat(2)), OP_GE),
+	} {
+		assert.Equal(t, expected, Format(c))
+	}
+
+	assert.Equal(t, "x - 2*y + 3", FormatExpression(y.MultiplyFloat(-2).Add(x).AddFloat(3)))
+	assert.Equal(t, "-4", FormatExpression(NewExpression(-4)))
+	assert.Equal(t, "max(x, y) - 1", FormatExpression(Max(x, y).AddFloat(-1)))
+}
+
+// Parse(Format(c)) has to give back an equivalent constraint for any
+// constraint the parser can write.
+func TestFormatRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	vr := StrictResolver{}
+	var variables []*Variable
+	for _, name := range []string{"x", "y", "a.left", "b.width", "title0.top", "_z", "ü"} {
+		vr[name] = NewVariable(name)
+		variables = append(variables, vr[name])
+	}
+
+	number := func() float64 {
+		switch random.Intn(4) {
+		case 0:
+			return float64(random.Intn(21) - 10)
+		case 1:
+			return random.NormFloat64() * 100
+		case 2:
+			return math.Ldexp(random.Float64()-0.5, random.Intn(120)-60)
+		}
+		return 0
+	}
+	strengths := []Strength{
+		Required, Strong, Medium, Weak, AlmostRequired,
+		CreateStrength(1, 0, 0, 2.5), CreateStrength(0, 0, 1, 0.125),
+		CreateStrengthWithDefaultWeight(3, 2, 1), CreateStrengthWithDefaultWeight(0, 0, 0),
+	}
+	expression := func() *Expression {
+		e := NewExpression(number())
+		for n := random.Intn(4); n > 0; n-- {
+			e.Terms = append(e.Terms, NewTerm(variables[random.Intn(len(variables))], number()))
+		}
+		return e
+	}
+
+	for i := 0; i < 2000; i++ {
+		e := expression()
+		op := []RelationalOperator{OP_LE, OP_GE, OP_EQ, OP_RANGE}[random.Intn(4)]
+		if op == OP_LE && random.Intn(3) == 0 {
+			e = e.Add(Max(expression(), expression()).MultiplyFloat(1 + random.Float64()))
+		}
+		var c *Constraint
+		if op == OP_RANGE {
+			c = newRange(e, math.Abs(number()), strengths[random.Intn(len(strengths))])
+		} else {
+			c = NewConstraint(e, op, strengths[random.Intn(len(strengths))])
+		}
+
+		formatted := Format(c)
+		parsed, err := NewConstraintParser().ParseConstraint(formatted, vr)
+		if !assert.NoError(t, err, formatted) {
+			continue
+		}
+		assert.Equal(t, formatted, Format(parsed))
+		assert.Equal(t, c.Op, parsed.Op, formatted)
+		assert.Equal(t, c.Strength, parsed.Strength, formatted)
+		assert.Equal(t, c.span, parsed.span, formatted)
+		if !isPiecewise(c) {
+			assert.True(t, sameExpression(c.expression, parsed.expression), formatted)
+		}
+	}
+}
+
+func isPiecewise(c *Constraint) bool {
+	for _, t := range c.expression.Terms {
+		if t.Variable.definition != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package cassgowary
+
+import (
+	"fmt"
+
+	"github.com/emirpasic/gods/sets/linkedhashset"
+	"github.com/pkg/errors"
+)
+
+// A ConstraintGroup is a named set of constraints that is activated and
+// deactivated as a unit, for example the constraints of one orientation
+// of a responsive layout. A constraint may belong to several groups, it
+// stays in the solver as long as one of its groups is active.
+type ConstraintGroup struct {
+	Name    string
+	solver  *Solver
+	members *linkedhashset.Set //*Constraint
+	active  bool
+}
+
+// NewConstraintGroup creates an inactive group with the given members.
+func (s *Solver) NewConstraintGroup(name string, members ...*Constraint) (*ConstraintGroup, error) {
+	if _, exists := s.groups.Get(name); exists {
+		return nil, fmt.Errorf("duplicate constraint group '%s'", name)
+	}
+
+	g := &ConstraintGroup{
+		Name:    name,
+		solver:  s,
+		members: linkedhashset.New(),
+	}
+	for _, c := range members {
+		g.members.Add(c)
+	}
+	s.groups.Put(name, g)
+	return g, nil
+}
+
+// ConstraintGroup returns the group with the given name.
+func (s *Solver) ConstraintGroup(name string) (*ConstraintGroup, bool) {
+	g, exists := s.groups.Get(name)
+	if !exists {
+		return nil, false
+	}
+	return g.(*ConstraintGroup), true
+}
+
+// ConstraintGroups returns all groups in the order they were created.
+func (s *Solver) ConstraintGroups() []*ConstraintGroup {
+	groups := make([]*ConstraintGroup, 0, s.groups.Size())
+	s.groups.Each(func(_, v interface{}) {
+		groups = append(groups, v.(*ConstraintGroup))
+	})
+	return groups
+}
+
+// RemoveConstraintGroup deactivates the group and forgets it.
+func (s *Solver) RemoveConstraintGroup(g *ConstraintGroup) error {
+	if err := s.UpdateGroups(nil, []*ConstraintGroup{g}); err != nil {
+		return err
+	}
+	s.groups.Remove(g.Name)
+	return nil
+}
+
+// UpdateGroups activates and deactivates groups in one batch.
+// Only the difference is applied: a constraint that is a member of both
+// a deactivated and an activated group stays in the solver untouched.
+// If a constraint can't be added, the changes already made are undone.
+func (s *Solver) UpdateGroups(activate, deactivate []*ConstraintGroup) error {
+	activated := map[*ConstraintGroup]bool{}
+	for _, g := range activate {
+		activated[g] = true
+	}
+	counted := map[*ConstraintGroup]bool{}
+	delta := map[*Constraint]int{}
+	order := []*Constraint{}
+	count := func(groups []*ConstraintGroup, wanted bool, step int) {
+		for _, g := range groups {
+			// A group is counted once, and one in both lists ends up active.
+			if g.active == wanted || counted[g] || step < 0 && activated[g] {
+				continue
+			}
+			counted[g] = true
+			g.members.Each(func(_ int, v interface{}) {
+				c := v.(*Constraint)
+				if _, exists := delta[c]; !exists {
+					order = append(order, c)
+				}
+				delta[c] += step
+			})
+		}
+	}
+	count(deactivate, false, -1)
+	count(activate, true, 1)
+
+	var removed, added []*Constraint
+	undo := func(cause error) error {
+		for i := len(added) - 1; i >= 0; i-- {
+			if err := s.remove(added[i]); err != nil {
+				return errors.Wrapf(err, "can't undo after: %v", cause)
+			}
+		}
+		for _, c := range removed {
+			if err := s.add(c, true); err != nil {
+				return errors.Wrapf(err, "can't undo after: %v", cause)
+			}
+		}
+		return cause
+	}
+
+	// Members added or removed with AddConstraint and RemoveConstraint
+	// are left as they are.
+	for _, c := range order {
+		if s.grouped[c] > 0 && s.grouped[c]+delta[c] <= 0 && s.HasConstraint(c) {
+			if err := s.remove(c); err != nil {
+				return undo(errors.Wrap(err, "can't deactivate constraint group"))
+			}
+			removed = append(removed, c)
+		}
+	}
+	for _, c := range order {
+		if s.grouped[c] <= 0 && s.grouped[c]+delta[c] > 0 && !s.HasConstraint(c) {
+			// A failed add leaves the tableau as it was.
+			if err := s.add(c, true); err != nil {
+				return undo(errors.Wrap(err, "can't activate constraint group"))
+			}
+			added = append(added, c)
+		}
+	}
+
+	for _, c := range order {
+		if s.grouped[c] += delta[c]; s.grouped[c] <= 0 {
+			delete(s.grouped, c)
+		}
+	}
+	for _, g := range deactivate {
+		g.active = false
+	}
+	for _, g := range activate {
+		g.active = true
+	}
+	return nil
+}
+
+// Activate adds the constraints of the group to the solver.
+func (g *ConstraintGroup) Activate() error {
+	return g.solver.UpdateGroups([]*ConstraintGroup{g}, nil)
+}
+
+// Deactivate removes the constraints of the group from the solver.
+func (g *ConstraintGroup) Deactivate() error {
+	return g.solver.UpdateGroups(nil, []*ConstraintGroup{g})
+}
+
+// IsActive reports whether the constraints of the group are in the solver.
+func (g *ConstraintGroup) IsActive() bool {
+	return g.active
+}
+
+// Members returns the constraints of the group.
+func (g *ConstraintGroup) Members() []*Constraint {
+	members := make([]*Constraint, 0, g.members.Size())
+	g.members.Each(func(_ int, v interface{}) {
+		members = append(members, v.(*Constraint))
+	})
+	return members
+}
+
+// Add adds constraints to the group, and to the solver if it is active.
+func (g *ConstraintGroup) Add(members ...*Constraint) error {
+	for _, c := range members {
+		if g.members.Contains(c) {
+			continue
+		}
+		if g.active {
+			if g.solver.grouped[c] == 0 && !g.solver.HasConstraint(c) {
+				if err := g.solver.add(c, true); err != nil {
+					return errors.Wrap(err, "can't add constraint to group")
+				}
+			}
+			g.solver.grouped[c]++
+		}
+		g.members.Add(c)
+	}
+	return nil
+}
+
+// Remove removes constraints from the group, and from the solver if the
+// group is active and no other active group holds them.
+func (g *ConstraintGroup) Remove(members ...*Constraint) error {
+	for _, c := range members {
+		if !g.members.Contains(c) {
+			continue
+		}
+		if g.active {
+			if g.solver.grouped[c] == 1 && g.solver.HasConstraint(c) {
+				if err := g.solver.remove(c); err != nil {
+					return errors.Wrap(err, "can't remove constraint from group")
+				}
+			}
+			if g.solver.grouped[c]--; g.solver.grouped[c] <= 0 {
+				delete(g.solver.grouped, c)
+			}
+		}
+		g.members.Remove(c)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraintGroupSwitch(t *testing.T) {
+	solver := NewSolver()
+	width := NewVariable("width")
+	height := NewVariable("height")
+	side := NewVariable("side")
+
+	shared := side.EqualsFloat(10)
+	portrait, err := solver.NewConstraintGroup("portrait",
+		width.EqualsFloat(300),
+		height.EqualsFloat(500),
+		shared,
+	)
+	assert.NoError(t, err)
+	landscape, err := solver.NewConstraintGroup("landscape",
+		width.EqualsFloat(500),
+		height.EqualsFloat(300),
+		shared,
+	)
+	assert.NoError(t, err)
+
+	_, err = solver.NewConstraintGroup("portrait")
+	assert.Error(t, err)
+
+	assert.NoError(t, portrait.Activate())
+	assert.True(t, portrait.IsActive())
+	assert.False(t, landscape.IsActive())
+	solver.UpdateVariables()
+	assert.InDelta(t, 300, width.Value, Epsilon)
+	assert.InDelta(t, 500, height.Value, Epsilon)
+
+	assert.NoError(t, solver.UpdateGroups(
+		[]*ConstraintGroup{landscape},
+		[]*ConstraintGroup{portrait},
+	))
+	assert.False(t, portrait.IsActive())
+	assert.True(t, landscape.IsActive())
+	assert.True(t, solver.HasConstraint(shared))
+	solver.UpdateVariables()
+	assert.InDelta(t, 500, width.Value, Epsilon)
+	assert.InDelta(t, 300, height.Value, Epsilon)
+	assert.InDelta(t, 10, side.Value, Epsilon)
+
+	assert.NoError(t, landscape.Deactivate())
+	assert.False(t, solver.HasConstraint(shared))
+
+	g, exists := solver.ConstraintGroup("landscape")
+	assert.True(t, exists)
+	assert.Equal(t, landscape, g)
+	assert.Equal(t, []*ConstraintGroup{portrait, landscape}, solver.ConstraintGroups())
+}
+
+func TestConstraintGroupMembers(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	c1 := x.GreaterThanOrEqualToFloat(10)
+	c2 := x.LessThanOrEqualToFloat(20)
+	g, err := solver.NewConstraintGroup("range", c1)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Activate())
+
+	assert.NoError(t, g.Add(c2))
+	assert.Equal(t, []*Constraint{c1, c2}, g.Members())
+	assert.True(t, solver.HasConstraint(c2))
+
+	assert.NoError(t, g.Remove(c1))
+	assert.Equal(t, []*Constraint{c2}, g.Members())
+	assert.False(t, solver.HasConstraint(c1))
+
+	assert.NoError(t, solver.RemoveConstraintGroup(g))
+	assert.False(t, solver.HasConstraint(c2))
+	assert.Empty(t, solver.ConstraintGroups())
+}
+
+func TestConstraintGroupRollback(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(10)))
+	ok := NewVariable("y").EqualsFloat(1)
+	g, err := solver.NewConstraintGroup("bad", ok, x.LessThanOrEqualToFloat(5))
+	assert.NoError(t, err)
+
+	assert.Error(t, g.Activate())
+	assert.False(t, g.IsActive())
+	assert.False(t, solver.HasConstraint(ok))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.NoError(t, solver.Validate(1e-6))
+}
+
+func TestConstraintGroupRollbackRestoresRemoved(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(10)))
+	assert.NoError(t, solver.AddEditVariable(x, Weak))
+	assert.NoError(t, solver.SuggestValue(x, 30))
+
+	wide, err := solver.NewConstraintGroup("wide", x.LessThanOrEqualToFloat(40))
+	assert.NoError(t, err)
+	narrow, err := solver.NewConstraintGroup("narrow", x.LessThanOrEqualToFloat(20), x.LessThanOrEqualToFloat(5))
+	assert.NoError(t, err)
+	assert.NoError(t, wide.Activate())
+
+	assert.Error(t, solver.UpdateGroups([]*ConstraintGroup{narrow}, []*ConstraintGroup{wide}))
+	assert.True(t, wide.IsActive())
+	assert.False(t, narrow.IsActive())
+	assert.NoError(t, solver.SuggestValue(x, 50))
+	solver.UpdateVariables()
+	assert.InDelta(t, 40, x.Value, Epsilon)
+	assert.NoError(t, solver.Validate(1e-6))
+}
+
+func TestConstraintGroupWithDirectCalls(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	c1 := x.GreaterThanOrEqualToFloat(10)
+	c2 := x.LessThanOrEqualToFloat(20)
+	g, err := solver.NewConstraintGroup("range", c1, c2)
+	assert.NoError(t, err)
+
+	// A member already in the solver is left there.
+	assert.NoError(t, solver.AddConstraint(c1))
+	assert.NoError(t, g.Activate())
+	assert.True(t, solver.HasConstraint(c1))
+	assert.True(t, solver.HasConstraint(c2))
+
+	// A member removed from the solver doesn't keep the group active.
+	assert.NoError(t, solver.RemoveConstraint(c2))
+	assert.NoError(t, g.Deactivate())
+	assert.False(t, g.IsActive())
+	assert.False(t, solver.HasConstraint(c1))
+	assert.False(t, solver.HasConstraint(c2))
+
+	assert.NoError(t, g.Activate())
+	assert.True(t, solver.HasConstraint(c2))
+	assert.NoError(t, solver.Validate(1e-6))
+}
+
+func TestConstraintGroupRepeated(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	c := x.EqualsFloat(10)
+	g, err := solver.NewConstraintGroup("fixed", c)
+	assert.NoError(t, err)
+
+	assert.NoError(t, solver.UpdateGroups([]*ConstraintGroup{g, g}, nil))
+	assert.True(t, solver.HasConstraint(c))
+	assert.NoError(t, g.Deactivate())
+	assert.False(t, solver.HasConstraint(c))
+
+	// A group in both lists ends up active.
+	assert.NoError(t, solver.UpdateGroups([]*ConstraintGroup{g}, []*ConstraintGroup{g, g}))
+	assert.True(t, g.IsActive())
+	assert.True(t, solver.HasConstraint(c))
+	assert.NoError(t, solver.UpdateGroups([]*ConstraintGroup{g}, []*ConstraintGroup{g}))
+	assert.True(t, g.IsActive())
+	assert.True(t, solver.HasConstraint(c))
+	assert.NoError(t, g.Deactivate())
+	assert.False(t, solver.HasConstraint(c))
+}
@@ -0,0 +1,166 @@
+package cassgowary
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdentifier
+	tokenRelation
+	tokenPunctuation
+)
+
+var tokenKindNames = map[tokenKind]string{
+	tokenEOF:         "end of input",
+	tokenNumber:      "number",
+	tokenIdentifier:  "identifier",
+	tokenRelation:    "relation",
+	tokenPunctuation: "punctuation",
+}
+
+// A token of the constraint language. The offset is the byte offset of
+// the token in the input.
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return tokenKindNames[t.kind]
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// Relations by their spelling, including the Unicode forms.
+var relationSpellings = map[string]RelationalOperator{
+	"==": OP_EQ,
+	"<=": OP_LE,
+	">=": OP_GE,
+	"≤":  OP_LE,
+	"≥":  OP_GE,
+}
+
+const punctuation = "+-*/^()!@:,={}"
+
+// Split the input into tokens, ending with a tokenEOF.
+func lex(input string) ([]token, error) {
+	return lexRange(input, 0, len(input))
+}
+
+// Split input[start:end] into tokens, with the offsets in the input.
+func lexRange(input string, start, end int) ([]token, error) {
+	src := input[:end]
+	tokens := []token{}
+	for i := start; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+
+		case strings.HasPrefix(src[i:], ".."):
+			i += 2
+			tokens = append(tokens, token{tokenPunctuation, src[start:i], start})
+
+		case isDigit(r) || r == '.' && i+1 < len(src) && isDigit(rune(src[i+1])):
+			i = scanNumber(src, i)
+			tokens = append(tokens, token{tokenNumber, src[start:i], start})
+
+		case isIdentifierStart(r):
+			i = scanIdentifier(src, i+size)
+			tokens = append(tokens, token{tokenIdentifier, src[start:i], start})
+
+		case r == '≤' || r == '≥':
+			i += size
+			tokens = append(tokens, token{tokenRelation, input[start:i], start})
+
+		case (r == '=' || r == '<' || r == '>') && strings.HasPrefix(src[i+1:], "="):
+			i += 2
+			tokens = append(tokens, token{tokenRelation, src[start:i], start})
+
+		case strings.ContainsRune(punctuation, r):
+			i += size
+			tokens = append(tokens, token{tokenPunctuation, src[start:i], start})
+
+		default:
+			err := newParseError(input, start, src[start:start+size])
+			err.Message = fmt.Sprintf("unexpected character %q", r)
+			return nil, err
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+// Scan the rest of an identifier, returning the offset after it. An
+// identifier may hold interpolations like thumb{i + 1}.top, a '{' without
+// a '}' on the same line ends it instead.
+func scanIdentifier(input string, i int) int {
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if r == '{' {
+			j := strings.IndexAny(input[i+1:], "{}\n")
+			if j < 0 || input[i+1+j] != '}' {
+				break
+			}
+			i += j + 2
+			continue
+		}
+		if !isIdentifierPart(r) || strings.HasPrefix(input[i:], "..") {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// Scan a decimal literal with an optional fraction and exponent,
+// returning the offset after it.
+func scanNumber(input string, i int) int {
+	digits := func() {
+		for i < len(input) && isDigit(rune(input[i])) {
+			i++
+		}
+	}
+
+	digits()
+	if i < len(input) && input[i] == '.' && !strings.HasPrefix(input[i:], "..") {
+		i++
+		digits()
+	}
+	if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+		j := i + 1
+		if j < len(input) && (input[j] == '+' || input[j] == '-') {
+			j++
+		}
+		if j < len(input) && isDigit(rune(input[j])) {
+			i = j
+			digits()
+		}
+	}
+	return i
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// Identifiers may contain dots for node.attribute names, and colons
+// for generated names.
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r) || r == '.' || r == ':'
+}
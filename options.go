@@ -0,0 +1,62 @@
+package cassgowary
+
+// SolverOption configures optional solver behavior in NewSolver.
+type SolverOption func(s *Solver)
+
+// WithImplicitStays makes the solver keep every variable at its last
+// solved value unless the constraints move it. Each variable gets a weak
+// stay constraint when it is first seen, and the stays follow the values
+// written by UpdateVariables. Removing a constraint then leaves the
+// variables it released where they were instead of snapping them to zero.
+func WithImplicitStays() SolverOption {
+	return func(s *Solver) {
+		s.implicitStays = true
+	}
+}
+
+// WithRecovery makes the solver recover from conflicting required
+// constraints instead of rejecting them. A required constraint that can't
+// be satisfied is added with the given strength, typically AlmostRequired,
+// and reported by BrokenConstraints and to the warning handler.
+func WithRecovery(strength Strength) SolverOption {
+	return func(s *Solver) {
+		s.recovery = true
+		s.recoveryStrength = ClipStrength(strength)
+	}
+}
+
+// WithWarningHandler sets the function called for every problem the
+// solver recovered from.
+func WithWarningHandler(handler func(w Warning)) SolverOption {
+	return func(s *Solver) {
+		s.warn = handler
+	}
+}
+
+// WithTracer reports the inner workings of the solver to the tracer.
+func WithTracer(tracer Tracer) SolverOption {
+	return func(s *Solver) {
+		s.tracer = tracer
+	}
+}
+
+// WithAutoRefresh makes the solver rebuild its tableau after the given
+// number of pivots, or as soon as Validate finds a residue above the
+// tolerance. A zero disables the respective check.
+func WithAutoRefresh(pivots int, tolerance float64) SolverOption {
+	return func(s *Solver) {
+		s.refreshPivots = pivots
+		s.refreshTolerance = tolerance
+	}
+}
+
+// WithPresolve keeps required equalities of the form x == y + c and
+// x == c out of the tableau when x is new to the solver. Constraints
+// added later see y + c or c in place of x, and UpdateVariables writes
+// the value of x from them. Layouts with many aliases and fixed sizes
+// end up with a much smaller tableau and the same results.
+func WithPresolve() SolverOption {
+	return func(s *Solver) {
+		s.presolve = true
+	}
+}
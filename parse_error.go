@@ -0,0 +1,70 @@
+package cassgowary
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError is returned for input the constraint parser can't read.
+// Offset is the byte offset of the offending token, Line and Column
+// are counted from 1, the column in characters. Token is the text of the
+// token, empty at the end of the input, and Expected describes what the
+// parser was looking for, if anything in particular.
+type ParseError struct {
+	Offset   int
+	Line     int
+	Column   int
+	Token    string
+	Expected string
+	Message  string
+	// Err is the underlying error, like the error of the resolver or
+	// NonLinearExpressionErr. It is returned by Unwrap, but not as the
+	// cause of pkg/errors, so errors.Cause of a ParseError is the
+	// ParseError itself.
+	Err error
+
+	source string
+}
+
+func newParseError(input string, offset int, tok string) *ParseError {
+	lineStart := strings.LastIndexByte(input[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(input[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(input)
+	} else {
+		lineEnd += offset
+	}
+
+	return &ParseError{
+		Offset: offset,
+		Line:   strings.Count(input[:offset], "\n") + 1,
+		Column: utf8.RuneCountInString(input[lineStart:offset]) + 1,
+		Token:  tok,
+		source: input[lineStart:lineEnd],
+	}
+}
+
+// Error renders the message with the source line and a caret under the
+// offending token.
+func (e *ParseError) Error() string {
+	// Tabs stay tabs, so the caret lines up however they are shown.
+	var indent strings.Builder
+	for i, r := range []rune(e.source) {
+		if i == e.Column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return fmt.Sprintf("line %d, column %d: %s\n\t%s\n\t%s^",
+		e.Line, e.Column, e.Message, e.source, indent.String(),
+	)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
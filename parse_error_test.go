@@ -0,0 +1,117 @@
+package cassgowary
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorPositions(t *testing.T) {
+	vr := MapResolver{}
+	for _, tc := range []struct {
+		input           string
+		offset, line    int
+		column          int
+		token, expected string
+	}{
+		{"x ==", 4, 1, 5, "", "expression"},
+		{"x == y)", 6, 1, 7, ")", "end of input"},
+		{"x == (y", 7, 1, 8, "", "')'"},
+		{"x y == 1", 2, 1, 3, "y", "relation"},
+		{"x == 1 !", 8, 1, 9, "", "strength"},
+		{"x == 1 $", 7, 1, 8, "$", ""},
+		{"ü ≤ y)", 8, 1, 6, ")", "end of input"},
+		{"x ==\n  (y", 9, 2, 5, "", "')'"},
+		{"x == y * z", 7, 1, 8, "*", ""},
+	} {
+		_, err := NewConstraintParser().ParseConstraint(tc.input, vr)
+		pe, ok := err.(*ParseError)
+		if !assert.True(t, ok, tc.input) {
+			continue
+		}
+		assert.Equal(t, tc.offset, pe.Offset, tc.input)
+		assert.Equal(t, tc.line, pe.Line, tc.input)
+		assert.Equal(t, tc.column, pe.Column, tc.input)
+		assert.Equal(t, tc.token, pe.Token, tc.input)
+		assert.Equal(t, tc.expected, pe.Expected, tc.input)
+	}
+}
+
+func TestParseErrorRendering(t *testing.T) {
+	vr := MapResolver{}
+	_, err := NewConstraintParser().ParseConstraint("x == y)", vr)
+	assert.EqualError(t, err, "line 1, column 7: expected end of input, found ')'\n"+
+		"\tx == y)\n"+
+		"\t      ^")
+
+	_, err = NewConstraintParser().ParseConstraint("\tx == y * z", vr)
+	assert.EqualError(t, err, "line 1, column 9: non-linear expression\n"+
+		"\t\tx == y * z\n"+
+		"\t\t       ^")
+}
+
+func TestParseErrorCause(t *testing.T) {
+	vr := StrictResolver{"x": NewVariable("x")}
+	_, err := NewConstraintParser().ParseConstraint("x == y", vr)
+	pe, ok := err.(*ParseError)
+	assert.True(t, ok)
+	assert.Equal(t, "y", pe.Token)
+	assert.Equal(t, 5, pe.Offset)
+	assert.Equal(t, UnknownVariableErr, underlyingCause(err))
+	assert.Equal(t, err, errors.Cause(err))
+
+	_, err = NewConstraintParser().ParseConstraint("x ==", vr)
+	assert.IsType(t, &ParseError{}, errors.Cause(err))
+}
+
+// Get the cause of the error underlying a parse error.
+func underlyingCause(err error) error {
+	pe, ok := err.(*ParseError)
+	if !ok {
+		return nil
+	}
+	return errors.Cause(pe.Err)
+}
+
+// The parser must return an error, never panic, whatever the input.
+func TestParseConstraintNeverPanics(t *testing.T) {
+	vr := MapResolver{}
+	pieces := []string{
+		"x", "y.top", "1", "2.5", ".5", "1e3", "1e", "1e+", "e", " ", "\n", "\t",
+		"+", "-", "*", "/", "^", "(", ")", "!", "@", ":", ",", "=", "==", "<", "<=",
+		">=", "≤", "≥", "!weak", "!strong", "\xff", "ü", "$", "0", "/0",
+	}
+	inputs := []string{
+		strings.Repeat("(", 100000),
+		strings.Repeat("-", 100000) + "x == 1",
+		"x == " + strings.Repeat("(", 5000) + "1" + strings.Repeat(")", 5000),
+		"x == 1e999",
+		"\xff\xfe",
+	}
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 20000; i++ {
+		var sb strings.Builder
+		for n := random.Intn(12); n >= 0; n-- {
+			sb.WriteString(pieces[random.Intn(len(pieces))])
+		}
+		inputs = append(inputs, sb.String())
+	}
+
+	for _, input := range inputs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("panic on %q: %v", input, r)
+				}
+			}()
+			c, err := NewConstraintParser().ParseConstraint(input, vr)
+			assert.True(t, (c == nil) != (err == nil), input)
+			if err != nil {
+				_ = err.Error()
+			}
+		}()
+	}
+}
@@ -0,0 +1,144 @@
+package cassgowary
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+type piecewiseKind int
+
+const (
+	piecewiseMin piecewiseKind = iota
+	piecewiseMax
+	piecewiseAbs
+)
+
+var piecewiseNames = map[piecewiseKind]string{
+	piecewiseMin: "min",
+	piecewiseMax: "max",
+	piecewiseAbs: "abs",
+}
+
+var piecewiseKinds = map[string]piecewiseKind{
+	"min": piecewiseMin,
+	"max": piecewiseMax,
+	"abs": piecewiseAbs,
+}
+
+// The uses which don't need integer variables.
+var piecewiseUses = map[piecewiseKind]string{
+	piecewiseMin: "an upper bound, as in y <= min(a, b)",
+	piecewiseMax: "a lower bound, as in y >= max(a, b)",
+	piecewiseAbs: "a lower bound, as in y >= abs(a)",
+}
+
+// The definition of an auxiliary variable standing for the min, max or
+// abs of its arguments.
+type piecewise struct {
+	kind piecewiseKind
+	args []*Expression
+}
+
+// Min returns an expression for the smallest of the expressions. It may
+// only be bounded from above, as in y <= Min(a, b), since any other use
+// needs integer variables to be exact. The solver rejects the others with
+// NonConvexExpressionErr.
+func Min(first *Expression, rest ...*Expression) *Expression {
+	return newPiecewise(piecewiseMin, append([]*Expression{first}, rest...))
+}
+
+// Max returns an expression for the largest of the expressions. It may
+// only be bounded from below, as in y >= Max(a, b).
+func Max(first *Expression, rest ...*Expression) *Expression {
+	return newPiecewise(piecewiseMax, append([]*Expression{first}, rest...))
+}
+
+// Abs returns an expression for the absolute value of the expression.
+// Like Max, it may only be bounded from below, as in gap >= Abs(a - b).
+func Abs(e *Expression) *Expression {
+	return newPiecewise(piecewiseAbs, []*Expression{e})
+}
+
+// Get an expression of the auxiliary variable for the definition. Constant
+// arguments are folded, so is a single argument.
+func newPiecewise(kind piecewiseKind, args []*Expression) *Expression {
+	if kind != piecewiseAbs && len(args) == 1 {
+		return args[0]
+	}
+
+	constant := true
+	for _, arg := range args {
+		constant = constant && isConstant(arg)
+	}
+	if constant {
+		value := args[0].Constant
+		for _, arg := range args[1:] {
+			if kind == piecewiseMin {
+				value = math.Min(value, arg.Constant)
+			} else {
+				value = math.Max(value, arg.Constant)
+			}
+		}
+		if kind == piecewiseAbs {
+			value = math.Abs(value)
+		}
+		return NewExpression(value)
+	}
+
+	v := NewVariable(piecewiseNames[kind])
+	v.definition = &piecewise{kind: kind, args: args}
+	return NewExpressionFrom(NewTermFrom(v))
+}
+
+// Get the constraints defining the auxiliary variables of the constraint,
+// rejecting the constraint if they can't define them exactly. The variable
+// which can't be defined is returned with the error.
+//
+// A constraint e <= 0 holds for some t >= max(a, b) exactly when it holds
+// for t = max(a, b), if t has a positive coefficient in e. So the variable
+// of a max only needs t >= a and t >= b, and likewise the variable of a
+// min with a negative coefficient only needs t <= a and t <= b. With the
+// other sign, or in an equality, the variable would have to be exactly
+// the max or min, which needs integer variables.
+func lowerPiecewise(c *Constraint) ([]*Constraint, *Variable, error) {
+	var definitions []*Constraint
+	for _, t := range c.expression.Terms {
+		d := t.Variable.definition
+		if d == nil || FloatNearZero(t.Coefficient) {
+			continue
+		}
+
+		name, use := piecewiseNames[d.kind], piecewiseUses[d.kind]
+		exact := (c.Op == OP_LE) == (t.Coefficient > 0)
+		if d.kind == piecewiseMin {
+			exact = !exact
+		}
+		if c.Op == OP_EQ || c.Op == OP_RANGE || !exact {
+			return nil, t.Variable, errors.Wrapf(NonConvexExpressionErr,
+				"%s can only be %s, anything else needs integer variables", name, use)
+		}
+
+		v := NewExpressionFrom(NewTermFrom(t.Variable))
+		args := d.args
+		if d.kind == piecewiseAbs {
+			args = []*Expression{args[0], args[0].Negate()}
+		}
+		for _, arg := range args {
+			var definition *Constraint
+			if d.kind == piecewiseMin {
+				definition = NewConstraintRequired(v.Subtract(arg), OP_LE)
+			} else {
+				definition = NewConstraintRequired(v.Subtract(arg), OP_GE)
+			}
+			// The arguments have the opposite sign of the variable.
+			nested, v, err := lowerPiecewise(NewConstraintRequired(arg.Negate(), definition.Op))
+			if err != nil {
+				return nil, v, err
+			}
+			definitions = append(definitions, nested...)
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions, nil, nil
+}
@@ -0,0 +1,158 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func variableExpression(v *Variable) *Expression {
+	return NewExpressionFrom(NewTermFrom(v))
+}
+
+func TestMaxLowerBound(t *testing.T) {
+	solver := NewSolver()
+	a, b, y := NewVariable("a"), NewVariable("b"), NewVariable("y")
+	assert.NoError(t, solver.AddConstraint(a.EqualsFloat(10)))
+	assert.NoError(t, solver.AddEditVariable(b, Strong))
+	assert.NoError(t, solver.AddConstraint(NewConstraint(variableExpression(y), OP_EQ, Weak)))
+	assert.NoError(t, solver.AddConstraint(y.GreaterThanOrEqualToExpression(Max(variableExpression(a), variableExpression(b)))))
+
+	for suggested, expected := range map[float64]float64{20: 20, 5: 10, 10: 10, -3: 10, 11: 11} {
+		assert.NoError(t, solver.SuggestValue(b, suggested))
+		solver.UpdateVariables()
+		assert.InDelta(t, expected, y.Value, Epsilon, "b = %v", suggested)
+	}
+}
+
+func TestMinUpperBound(t *testing.T) {
+	solver := NewSolver()
+	width, container := NewVariable("width"), NewVariable("container.width")
+	assert.NoError(t, solver.AddEditVariable(container, Strong))
+	assert.NoError(t, solver.AddConstraint(width.EqualsFloat(1000).NewModifyStrength(Weak)))
+	bound := Min(NewExpression(300), NewExpressionFrom(container.Multiply(0.4)))
+	assert.NoError(t, solver.AddConstraint(width.LessThanOrEqualToExpression(bound)))
+
+	for suggested, expected := range map[float64]float64{500: 200, 1000: 300, 750: 300, 100: 40} {
+		assert.NoError(t, solver.SuggestValue(container, suggested))
+		solver.UpdateVariables()
+		assert.InDelta(t, expected, width.Value, Epsilon, "container = %v", suggested)
+	}
+}
+
+func TestAbsLowerBound(t *testing.T) {
+	solver := NewSolver()
+	a, b, gap := NewVariable("a"), NewVariable("b"), NewVariable("gap")
+	assert.NoError(t, solver.AddEditVariable(a, Strong))
+	assert.NoError(t, solver.AddConstraint(b.EqualsFloat(10)))
+	assert.NoError(t, solver.AddConstraint(NewConstraint(variableExpression(gap), OP_EQ, Weak)))
+	assert.NoError(t, solver.AddConstraint(gap.GreaterThanOrEqualToExpression(Abs(a.Subtract(b)))))
+
+	for suggested, expected := range map[float64]float64{3: 7, 10: 0, 25: 15} {
+		assert.NoError(t, solver.SuggestValue(a, suggested))
+		solver.UpdateVariables()
+		assert.InDelta(t, expected, gap.Value, Epsilon, "a = %v", suggested)
+	}
+}
+
+func TestPiecewiseRemovedWithConstraint(t *testing.T) {
+	solver := NewSolver()
+	a, b, y := NewVariable("a"), NewVariable("b"), NewVariable("y")
+	assert.NoError(t, solver.AddConstraint(a.EqualsFloat(10)))
+	assert.NoError(t, solver.AddConstraint(b.EqualsFloat(20)))
+	size := tableauSize(solver)
+
+	c := y.GreaterThanOrEqualToExpression(Max(variableExpression(a), variableExpression(b)))
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.Error(t, solver.AddConstraint(c))
+	assert.Equal(t, 5, solver.cns.Size())
+
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, size, tableauSize(solver))
+	assert.Equal(t, 2, solver.cns.Size())
+	assert.Empty(t, solver.definitions)
+}
+
+func TestPiecewiseRejected(t *testing.T) {
+	a, b, c, y := NewVariable("a"), NewVariable("b"), NewVariable("c"), NewVariable("y")
+	max := Max(variableExpression(a), variableExpression(b))
+	min := Min(variableExpression(a), variableExpression(b))
+	for _, cn := range []*Constraint{
+		y.EqualsExpression(min),
+		y.LessThanOrEqualToExpression(max),
+		y.GreaterThanOrEqualToExpression(min),
+		y.LessThanOrEqualToExpression(Abs(variableExpression(a))),
+		Between(0, max, 10),
+		y.GreaterThanOrEqualToExpression(Max(variableExpression(a), Min(variableExpression(b), variableExpression(c)))),
+	} {
+		solver := NewSolver()
+		err := solver.AddConstraint(cn)
+		assert.Equal(t, NonConvexExpressionErr, errors.Cause(err), "%v", cn)
+		assert.Equal(t, 0, solver.cns.Size())
+	}
+}
+
+func TestPiecewiseConstantsFolded(t *testing.T) {
+	assert.Equal(t, NewExpression(5), Max(NewExpression(3), NewExpression(5)))
+	assert.Equal(t, NewExpression(3), Min(NewExpression(3), NewExpression(5)))
+	assert.Equal(t, NewExpression(2), Abs(NewExpression(-2)))
+	x := variableExpression(NewVariable("x"))
+	assert.Equal(t, x, Max(x))
+}
+
+func TestParsePiecewise(t *testing.T) {
+	solver := NewSolver()
+	resolver := NewNodeResolver(solver)
+	parser := NewConstraintParser()
+	for _, input := range []string{
+		"container.width == 500",
+		"a.left == 10",
+		"b.left == 40",
+		"a.width == 0",
+		"b.width == 0",
+		"title.width == 1000 !weak",
+		"title.width <= min(300, container.width * 0.4)",
+		"gap.width == 0 !weak",
+		"gap.width >= abs(a.centerX - b.centerX)",
+	} {
+		c, err := parser.ParseConstraint(input, resolver)
+		if assert.NoError(t, err, input) {
+			assert.NoError(t, solver.AddConstraint(c), input)
+		}
+	}
+	solver.UpdateVariables()
+	assert.InDelta(t, 200, resolver.Nodes["title"]["width"].Value, Epsilon)
+	assert.InDelta(t, 30, resolver.Nodes["gap"]["width"].Value, Epsilon)
+
+	c, err := parser.ParseConstraint("min <= max + abs", MapResolver{})
+	assert.NoError(t, err)
+	assert.Len(t, c.expression.Terms, 3)
+}
+
+func TestParsePiecewiseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input   string
+		column  int
+		message string
+	}{
+		{"width == min(300, x)", 10, "min can only be an upper bound, as in y <= min(a, b), anything else needs integer variables: non-convex expression"},
+		{"y <= 2 * max(a, b) - 1", 10, "max can only be a lower bound"},
+		{"y >= max(a, 1 + min(b, c))", 17, "min can only be an upper bound"},
+		{"0 <= abs(x) <= 10", 6, "abs can only be a lower bound"},
+		{"y >= abs(a, b)", 6, "abs takes one argument, found 2"},
+		{"y >= max(a b)", 12, "expected ',' or ')', found 'b'"},
+		{"y >= max(a, b", 14, "expected ',' or ')', found end of input"},
+	} {
+		_, err := NewConstraintParser().ParseConstraint(tc.input, MapResolver{})
+		pe, ok := err.(*ParseError)
+		if !assert.True(t, ok, tc.input) {
+			continue
+		}
+		assert.Equal(t, tc.column, pe.Column, tc.input)
+		assert.Contains(t, pe.Message, tc.message, tc.input)
+	}
+
+	_, err := NewConstraintParser().ParseConstraint("width == min(300, x)", MapResolver{})
+	assert.Equal(t, NonConvexExpressionErr, underlyingCause(err))
+}
@@ -0,0 +1,285 @@
+package cassgowary
+
+// An alias is a variable the presolve keeps out of the tableau because a
+// required equality determines it: variable == target + constant, or
+// variable == constant if the target is nil. The target is the variable
+// of the constraint as written, which may be an alias itself, so removing
+// the alias of the target leaves this one following the target.
+type alias struct {
+	constraint *Constraint
+	variable   *Variable
+	target     *Variable
+	constant   float64
+}
+
+// Find the alias the constraint defines. That is a required equality of
+// the form x == y + c or x == c, where x is new to the solver, so no row
+// has to be rewritten when it is substituted away.
+func (s *Solver) aliasFor(c *Constraint) *alias {
+	if c.Op != OP_EQ || c.Strength < Required {
+		return nil
+	}
+
+	e := c.expression
+	terms := make(Terms, 0, 2)
+	for _, t := range e.Terms {
+		if !FloatNearZero(t.Coefficient) {
+			terms = append(terms, t)
+		}
+	}
+
+	switch len(terms) {
+	case 1:
+		x := terms[0]
+		if !s.isFresh(x.Variable) {
+			return nil
+		}
+		return &alias{
+			constraint: c,
+			variable:   x.Variable,
+			constant:   -e.Constant / x.Coefficient,
+		}
+
+	case 2:
+		x, y := terms[0], terms[1]
+		if x.Coefficient != -y.Coefficient {
+			return nil
+		}
+		if !s.isFresh(x.Variable) {
+			x, y = y, x
+		}
+		if !s.isFresh(x.Variable) {
+			return nil
+		}
+		// a*x - a*y + k == 0 gives x == y - k/a
+		return &alias{
+			constraint: c,
+			variable:   x.Variable,
+			target:     y.Variable,
+			constant:   -e.Constant / x.Coefficient,
+		}
+	}
+	return nil
+}
+
+// Test whether nothing in the solver mentions the variable yet.
+func (s *Solver) isFresh(v *Variable) bool {
+	if _, exists := s.vars.Get(v); exists {
+		return false
+	}
+	if _, exists := s.aliases[v]; exists {
+		return false
+	}
+	return s.refs[v] == 0
+}
+
+func (s *Solver) addAlias(a *alias) {
+	s.aliases[a.variable] = a
+	s.aliasCns.Put(a.constraint, a)
+	if a.target != nil {
+		s.refs[a.target]++
+	}
+
+	if s.tracer != nil {
+		s.tracer.ConstraintAdded(a.constraint)
+	}
+}
+
+// Remove the alias. The variable becomes a variable of its own, so the
+// constraints that were mentioning it through the alias now mention it
+// instead, and their components are regrouped.
+func (s *Solver) removeAlias(a *alias) error {
+	var rewritten []*Constraint
+	var components []*component
+	var released []*Variable
+	s.cns.Each(func(k, v interface{}) {
+		c, owner := k.(*Constraint), v.(*tag).owner
+		if !s.mentionsThrough(c, a.variable) {
+			return
+		}
+		rewritten = append(rewritten, c)
+		if !containsComponent(components, owner) {
+			components = append(components, owner)
+		}
+		for _, v := range s.resolved(c).variables() {
+			s.refs[v]--
+			released = append(released, v)
+		}
+	})
+
+	delete(s.aliases, a.variable)
+	s.aliasCns.Remove(a.constraint)
+	if s.tracer != nil {
+		s.tracer.ConstraintRemoved(a.constraint)
+	}
+
+	if a.target != nil {
+		s.refs[a.target]--
+		released = append(released, a.target)
+	}
+	for _, c := range rewritten {
+		for _, v := range s.resolved(c).variables() {
+			s.refs[v]++
+			// Resolved terms may have cancelled out before, so the
+			// constraint can join the components of its variables.
+			if owner, exists := s.owners.Get(v); exists && !containsComponent(components, owner.(*component)) {
+				components = append(components, owner.(*component))
+			}
+		}
+	}
+	for _, v := range released {
+		if owner, exists := s.owners.Get(v); exists {
+			s.releaseVariables(owner.(*component), []*Variable{v})
+		} else if s.refs[v] == 0 {
+			delete(s.refs, v)
+		}
+	}
+
+	if len(components) > 0 {
+		return s.regroup(components)
+	}
+	return nil
+}
+
+// Test whether a term of the constraint follows the aliases through the
+// variable.
+func (s *Solver) mentionsThrough(c *Constraint, through *Variable) bool {
+	for _, t := range c.expression.Terms {
+		if s.aliasesThrough(t.Variable, through) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsComponent(components []*component, cp *component) bool {
+	for _, other := range components {
+		if other == cp {
+			return true
+		}
+	}
+	return false
+}
+
+// Test whether following the aliases from v passes through the variable.
+func (s *Solver) aliasesThrough(v, through *Variable) bool {
+	for v != nil {
+		if v == through {
+			return true
+		}
+		a, exists := s.aliases[v]
+		if !exists {
+			return false
+		}
+		v = a.target
+	}
+	return false
+}
+
+// Follow the aliases from the variable. The variable equals the returned
+// variable plus the offset, or just the offset if that variable is nil.
+func (s *Solver) resolveVariable(v *Variable) (*Variable, float64) {
+	offset := float64(0)
+	for {
+		a, exists := s.aliases[v]
+		if !exists {
+			return v, offset
+		}
+		offset += a.constant
+		if a.target == nil {
+			return nil, offset
+		}
+		v = a.target
+	}
+}
+
+// Get the constraint with its aliases replaced by what they stand for.
+// Without aliases in it, this is the constraint itself.
+func (s *Solver) resolved(c *Constraint) *Constraint {
+	if len(s.aliases) == 0 {
+		return c
+	}
+
+	found := false
+	for _, t := range c.expression.Terms {
+		if _, exists := s.aliases[t.Variable]; exists {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return c
+	}
+
+	e := NewExpression(c.expression.Constant)
+	for _, t := range c.expression.Terms {
+		v, offset := s.resolveVariable(t.Variable)
+		e.Constant += t.Coefficient * offset
+		if v != nil {
+			e.Terms = append(e.Terms, NewTerm(v, t.Coefficient))
+		}
+	}
+	rc := NewConstraint(e, c.Op, c.Strength)
+	rc.span = c.span
+	return rc
+}
+
+// Get the variables the constraint mentions once aliases are resolved.
+// An alias mentions the variable its target resolves to.
+func (s *Solver) mentionedVariables(c *Constraint) []*Variable {
+	if x, exists := s.aliasCns.Get(c); exists {
+		if target, _ := s.resolveVariable(x.(*alias).variable); target != nil {
+			return []*Variable{target}
+		}
+		return nil
+	}
+	return s.resolved(c).variables()
+}
+
+// Write the values of the aliases, once the other variables are updated.
+// A target outside the tableau is a free variable, which is zero.
+func (s *Solver) updateAliases() {
+	s.aliasCns.Each(func(_, x interface{}) {
+		a := x.(*alias)
+		target, offset := s.resolveVariable(a.variable)
+		a.variable.Value = offset
+		if target != nil {
+			if _, exists := s.vars.Get(target); !exists {
+				target.Value = 0
+			}
+			a.variable.Value += target.Value
+		}
+	})
+}
+
+// Regroup the constraints of the components into new components and
+// rebuild their tableaux, after a change to the aliases changed the
+// variables they mention.
+func (s *Solver) regroup(components []*component) error {
+	all := newComponent(s)
+	for _, cp := range components {
+		s.components.Remove(cp)
+		cp.cns.Each(func(k, v interface{}) {
+			v.(*tag).owner = all
+			all.cns.Put(k, v)
+		})
+		cp.vars.Each(func(_ int, v interface{}) {
+			s.owners.Remove(v)
+		})
+	}
+	all.cns.Each(func(k, _ interface{}) {
+		for _, v := range s.resolved(k.(*Constraint)).variables() {
+			all.vars.Add(v)
+			s.owners.Put(v, all)
+		}
+	})
+
+	s.components.Add(all)
+	if err := s.splitComponent(all); err != nil {
+		return err
+	}
+	if s.components.Contains(all) {
+		return s.rebuild(all)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Build the grid layout and return its nodes, along with whether each
+// constraint was accepted.
+func buildGridLayout(t *testing.T, solver *Solver) (map[string]map[string]*Variable, []bool) {
+	variableResolver := NewNodeResolver(solver)
+	cp := NewConstraintParser()
+
+	all := append([]string{}, constraints...)
+	all = append(all,
+		"container.width == 300",
+		"title0.intrinsicHeight == 100",
+		"title1.intrinsicHeight == 110",
+		"title2.intrinsicHeight == 120",
+		"title3.intrinsicHeight == 130",
+		"title4.intrinsicHeight == 140",
+		"title5.intrinsicHeight == 150",
+		"more.intrinsicHeight == 160",
+	)
+	accepted := make([]bool, len(all))
+	for i, constraint := range all {
+		c, err := cp.ParseConstraint(constraint, variableResolver)
+		assert.NoError(t, err)
+		accepted[i] = solver.AddConstraint(c) == nil
+	}
+	solver.UpdateVariables()
+	return variableResolver.Nodes, accepted
+}
+
+func tableauSize(solver *Solver) int {
+	rows := 0
+	solver.components.Each(func(_ int, v interface{}) {
+		rows += v.(*component).rows.Size()
+	})
+	return rows
+}
+
+func TestPresolveGridLayout(t *testing.T) {
+	plain := NewSolver()
+	plainNodes, plainAccepted := buildGridLayout(t, plain)
+	presolved := NewSolver(WithPresolve())
+	presolvedNodes, presolvedAccepted := buildGridLayout(t, presolved)
+
+	assert.Equal(t, plainAccepted, presolvedAccepted)
+
+	for name, node := range plainNodes {
+		for property, v := range node {
+			assert.InDelta(t, v.Value, presolvedNodes[name][property].Value, 1e-9, "%s.%s", name, property)
+		}
+	}
+	assert.True(t, presolved.aliasCns.Size() > 10)
+	assert.True(t, tableauSize(presolved) < tableauSize(plain)-10, "%d rows, %d without presolve", tableauSize(presolved), tableauSize(plain))
+	assert.NoError(t, presolved.Validate(1e-9))
+}
+
+func TestPresolveAliases(t *testing.T) {
+	solver := NewSolver(WithPresolve())
+	x := NewVariable("x")
+	y := NewVariable("y")
+	z := NewVariable("z")
+
+	assert.NoError(t, solver.AddConstraint(y.GreaterThanOrEqualToFloat(10)))
+	cx := x.EqualsExpression(y.AddFloat(5))
+	assert.NoError(t, solver.AddConstraint(cx))
+	cz := z.EqualsFloat(7)
+	assert.NoError(t, solver.AddConstraint(cz))
+	assert.True(t, solver.HasConstraint(cx))
+	assert.True(t, solver.HasConstraint(cz))
+	assert.Equal(t, 2, solver.aliasCns.Size())
+	assert.Equal(t, DuplicateConstraintErr(cx), solver.AddConstraint(cx))
+
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 40))
+	assert.NoError(t, solver.AddConstraint(y.LessThanOrEqualToExpression(z.Multiply(5).AddFloat(0))))
+	solver.UpdateVariables()
+	assert.InDelta(t, 35, y.Value, Epsilon)
+	assert.InDelta(t, 40, x.Value, Epsilon)
+	assert.InDelta(t, 7, z.Value, Epsilon)
+
+	assert.NoError(t, solver.SuggestValue(x, 50))
+	solver.UpdateVariables()
+	assert.InDelta(t, 35, y.Value, Epsilon)
+	assert.InDelta(t, 40, x.Value, Epsilon)
+
+	// Without the alias x is free to follow the edit.
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	assert.False(t, solver.HasConstraint(cx))
+	solver.UpdateVariables()
+	assert.InDelta(t, 50, x.Value, Epsilon)
+	assert.True(t, y.Value >= 10-Epsilon && y.Value <= 35+Epsilon)
+
+	// Without the fixed value z no longer limits y.
+	assert.NoError(t, solver.RemoveConstraint(cz))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(-5))))
+	solver.UpdateVariables()
+	assert.InDelta(t, 50, x.Value, Epsilon)
+	assert.InDelta(t, 45, y.Value, Epsilon)
+	assertColumnsConsistent(t, solver)
+}
+
+func TestPresolveAliasOfAlias(t *testing.T) {
+	solver := NewSolver(WithPresolve())
+	x := NewVariable("x")
+	y := NewVariable("y")
+	w := NewVariable("w")
+	v := NewVariable("v")
+	u := NewVariable("u")
+
+	assert.NoError(t, solver.AddConstraint(y.EqualsFloat(10).NewModifyStrength(Weak)))
+	cx := x.EqualsExpression(y.AddFloat(5))
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(w.EqualsExpression(x.AddFloat(1))))
+	assert.NoError(t, solver.AddConstraint(v.EqualsTerm(*w.Multiply(2))))
+	assert.NoError(t, solver.AddConstraint(u.GreaterThanOrEqualToFloat(3)))
+	assert.Equal(t, 2, solver.aliasCns.Size())
+	unrelated, _ := solver.owners.Get(u)
+	solver.UpdateVariables()
+	assert.InDelta(t, 16, w.Value, Epsilon)
+	assert.InDelta(t, 32, v.Value, Epsilon)
+
+	// w follows x once x is defined differently.
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(100)))
+	solver.UpdateVariables()
+	assert.InDelta(t, 100, x.Value, Epsilon)
+	assert.InDelta(t, 101, w.Value, Epsilon)
+	assert.InDelta(t, 202, v.Value, Epsilon)
+	assert.InDelta(t, 10, y.Value, Epsilon)
+	assert.NoError(t, solver.Validate(1e-9))
+	// The component of u had nothing to do with the alias.
+	owner, _ := solver.owners.Get(u)
+	assert.True(t, owner == unrelated)
+	assertColumnsConsistent(t, solver)
+}
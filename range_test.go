@@ -0,0 +1,196 @@
+package cassgowary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBetweenClampsEdits(t *testing.T) {
+	for _, presolve := range []bool{false, true} {
+		var options []SolverOption
+		if presolve {
+			options = append(options, WithPresolve())
+		}
+		solver := NewSolver(options...)
+		x := NewVariable("x")
+		assert.NoError(t, solver.AddConstraint(Between(10, NewExpressionFrom(NewTermFrom(x)), 20)))
+		assert.NoError(t, solver.AddEditVariable(x, Strong))
+
+		for suggested, expected := range map[float64]float64{5: 10, 15: 15, 25: 20, 10: 10} {
+			assert.NoError(t, solver.SuggestValue(x, suggested))
+			solver.UpdateVariables()
+			assert.InDelta(t, expected, x.Value, Epsilon, "suggested %v", suggested)
+			assert.NoError(t, solver.Validate(1e-9))
+		}
+	}
+}
+
+func TestBetweenRemovedAsUnit(t *testing.T) {
+	solver := NewSolver()
+	x, y := NewVariable("x"), NewVariable("y")
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(1))))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	size := tableauSize(solver)
+
+	c := Between(0, NewExpressionFrom(NewTermFrom(x)), 100)
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.Equal(t, size+2, tableauSize(solver))
+	assert.NoError(t, solver.SuggestValue(x, 150))
+	solver.UpdateVariables()
+	assert.InDelta(t, 100, x.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, size, tableauSize(solver))
+	assert.NoError(t, solver.SuggestValue(x, 150))
+	solver.UpdateVariables()
+	assert.InDelta(t, 150, x.Value, Epsilon)
+	assert.InDelta(t, 151, y.Value, Epsilon)
+	assert.Error(t, solver.RemoveConstraint(c))
+}
+
+func TestBetweenScaled(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	assert.NoError(t, solver.AddConstraint(Between(-1, NewExpressionFrom(x.Multiply(0.001)), 1)))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 5000))
+	solver.UpdateVariables()
+	assert.InDelta(t, 1000, x.Value, 1e-6)
+	assert.NoError(t, solver.SuggestValue(x, -5000))
+	solver.UpdateVariables()
+	assert.InDelta(t, -1000, x.Value, 1e-6)
+}
+
+func TestSoftBetween(t *testing.T) {
+	for _, tc := range []struct {
+		edit     Strength
+		expected float64
+	}{
+		{Strong, 25},
+		{Weak, 20},
+	} {
+		solver := NewSolver()
+		x := NewVariable("x")
+		c := Between(10, NewExpressionFrom(NewTermFrom(x)), 20).NewModifyStrength(Medium)
+		assert.NoError(t, solver.AddConstraint(c))
+		assert.NoError(t, solver.AddEditVariable(x, tc.edit))
+		assert.NoError(t, solver.SuggestValue(x, 25))
+		solver.UpdateVariables()
+		assert.InDelta(t, tc.expected, x.Value, Epsilon)
+
+		assert.NoError(t, solver.RemoveConstraint(c))
+		assert.NoError(t, solver.SuggestValue(x, 5))
+		solver.UpdateVariables()
+		assert.InDelta(t, 5, x.Value, Epsilon)
+	}
+}
+
+func TestBetweenUnsatisfiable(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	assert.True(t, IsUnsatisfiable(solver.AddConstraint(Between(20, NewExpressionFrom(NewTermFrom(x)), 10))))
+
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(30)))
+	err := solver.AddConstraint(Between(0, NewExpressionFrom(NewTermFrom(x)), 10))
+	assert.True(t, IsUnsatisfiable(err))
+	solver.UpdateVariables()
+	assert.InDelta(t, 30, x.Value, Epsilon)
+}
+
+func TestParseChainedConstraints(t *testing.T) {
+	parser := NewConstraintParser()
+	resolver := MapResolver{}
+
+	cns, err := parser.ParseConstraints("0 <= x <= container.width", resolver)
+	assert.NoError(t, err)
+	if assert.Len(t, cns, 2) {
+		assertSameConstraint(t, NewExpression(0).LessThanOrEqualTo(NewExpressionFrom(NewTermFrom(resolver["x"]))), cns[0])
+		assertSameConstraint(t, resolver["x"].LessThanOrEqualTo(resolver["container.width"]), cns[1])
+	}
+
+	cns, err = parser.ParseConstraints("a.left <= b.left <= c.left !weak", resolver)
+	assert.NoError(t, err)
+	if assert.Len(t, cns, 2) {
+		for _, c := range cns {
+			assert.Equal(t, OP_LE, c.Op)
+			assert.Equal(t, Weak, c.Strength)
+		}
+	}
+
+	cns, err = parser.ParseConstraints("x == y == 2", resolver)
+	assert.NoError(t, err)
+	assert.Len(t, cns, 2)
+
+	for input, span := range map[string]float64{
+		"10 <= x <= 20":               10,
+		"20 >= x >= 10":               10,
+		"x - 5 ≤ y ≤ x + 5":           10,
+		"(x + 1) * 2 <= y <= 2*x + 3": 1,
+	} {
+		cns, err := parser.ParseConstraints(input, resolver)
+		assert.NoError(t, err, input)
+		if assert.Len(t, cns, 1, input) {
+			assert.Equal(t, OP_RANGE, cns[0].Op, input)
+			assert.InDelta(t, span, cns[0].span, Epsilon, input)
+		}
+	}
+
+	c, err := parser.ParseConstraint("10 <= x <= 20 !strong", resolver)
+	assert.NoError(t, err)
+	assert.Equal(t, OP_RANGE, c.Op)
+	assert.Equal(t, Strong, c.Strength)
+
+	_, err = parser.ParseConstraint("0 <= x <= y", resolver)
+	if assert.IsType(t, &ParseError{}, err) {
+		assert.Equal(t, 8, err.(*ParseError).Column)
+	}
+}
+
+func TestParsedRangeSolves(t *testing.T) {
+	solver := NewSolver()
+	resolver := MapResolver{}
+	c, err := NewConstraintParser().ParseConstraint("x - 5 <= y <= x + 5", resolver)
+	assert.NoError(t, err)
+	x, y := resolver["x"], resolver["y"]
+
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(100)))
+	assert.NoError(t, solver.AddEditVariable(y, Strong))
+	assert.NoError(t, solver.SuggestValue(y, 0))
+	solver.UpdateVariables()
+	assert.InDelta(t, 95, y.Value, Epsilon)
+	assert.NoError(t, solver.SuggestValue(y, 200))
+	solver.UpdateVariables()
+	assert.InDelta(t, 105, y.Value, Epsilon)
+}
+
+func TestRecordRange(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(NewSolver(), &buf)
+	x := NewVariable("x")
+	assert.NoError(t, rec.AddConstraint(Between(10, NewExpressionFrom(NewTermFrom(x)), 20)))
+	assert.NoError(t, rec.AddEditVariable(x, Strong))
+	assert.NoError(t, rec.SuggestValue(x, 30))
+	rec.UpdateVariables()
+	assert.Contains(t, buf.String(), "constraint c0 10 <= v0 <= 20\n")
+
+	recording, err := ReadRecording(&buf)
+	assert.NoError(t, err)
+	assert.NoError(t, recording.Replay(NewSolver()))
+}
+
+func TestExplainRange(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	assert.NoError(t, solver.AddConstraint(Between(10, NewExpressionFrom(NewTermFrom(x)), 20)))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 30))
+
+	x.Value = 0
+	solver.UpdateVariables()
+	explanation := solver.Explain(x)
+	assert.InDelta(t, 20, explanation.Value, Epsilon)
+	assert.Contains(t, explanation.String(), "slack(10 <= x <= 20)")
+}
@@ -2,24 +2,9 @@ package cassgowary
 
 import (
 	"log"
-	"strconv"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-
-	"github.com/pkg/errors"
-)
-
-const (
-	LEFT    = "left"
-	RIGHT   = "right"
-	TOP     = "top"
-	BOTTOM  = "bottom"
-	HEIGHT  = "height"
-	WIDTH   = "width"
-	CENTERX = "centerX"
-	CENTERY = "centerY"
 )
 
 var constraints = []string{
@@ -112,78 +97,10 @@ var constraints = []string{
 	"container.height == more.bottom + container.buttonPadding",
 }
 
-type nodeMap map[string]*Variable
-type nodesMap map[string]nodeMap
-type gridVariableResolver struct {
-	solver *Solver
-	nodes  nodesMap
-}
-
-func (vr *gridVariableResolver) getVariableFromNode(node nodeMap, variableName string) *Variable {
-	if v, exists := node[variableName]; exists {
-		return v
-	}
-	v := NewVariable(variableName)
-	node[variableName] = v
-
-	switch variableName {
-	case RIGHT:
-		v2 := vr.getVariableFromNode(node, LEFT)
-		v3 := vr.getVariableFromNode(node, WIDTH)
-		e := v2.Add(v3)
-		c := v.EqualsExpression(e)
-		vr.solver.AddConstraint(c)
-	case BOTTOM:
-		v2 := vr.getVariableFromNode(node, TOP)
-		v3 := vr.getVariableFromNode(node, HEIGHT)
-		e := v2.Add(v3)
-		c := v.EqualsExpression(e)
-		vr.solver.AddConstraint(c)
-	}
-	return v
-}
-
-func (vr *gridVariableResolver) getNode(nodeName string) map[string]*Variable {
-	if node, exists := vr.nodes[nodeName]; exists {
-		return node
-	}
-
-	node := map[string]*Variable{
-		nodeName: nil,
-	}
-	vr.nodes[nodeName] = node
-	return node
-}
-
-func (vr *gridVariableResolver) ResolveVariable(name string) (*Variable, error) {
-	arr := strings.Split(name, ".")
-	if len(arr) == 2 {
-		nodeName, propertyName := arr[0], arr[1]
-		node := vr.getNode(nodeName)
-		v := vr.getVariableFromNode(node, propertyName)
-		return v, nil
-	}
-	return nil, errors.New("can't resolve variable")
-}
-
-func (vr *gridVariableResolver) ResolveConstant(name string) (*Expression, error) {
-
-	f, err := strconv.ParseFloat(name, 64)
-	if err != nil {
-		return nil, errors.Wrapf(err, "can't parse '%s'", name)
-	}
-	e := NewExpression(f)
-	return e, nil
-}
-
-func createGridVariableResolver(solver *Solver, nodes nodesMap) VariableResolver {
-	return &gridVariableResolver{solver, nodes}
-}
-
 func TestGridLayout(t *testing.T) {
 	solver := NewSolver()
-	nodes := nodesMap{}
-	variableResolver := createGridVariableResolver(solver, nodes)
+	variableResolver := NewNodeResolver(solver)
+	nodes := variableResolver.Nodes
 
 	cp := NewConstraintParser()
 	for _, constraint := range constraints {
@@ -230,14 +147,14 @@ func TestGridLayout(t *testing.T) {
 	assert.InDelta(t, 20, nodes["thumb1"]["top"].Value, Epsilon)
 	assert.InDelta(t, 85, nodes["title0"]["top"].Value, Epsilon)
 	assert.InDelta(t, 85, nodes["title1"]["top"].Value, Epsilon)
-	assert.InDelta(t, 200, nodes["thumb2"]["top"].Value, Epsilon)
-	assert.InDelta(t, 200, nodes["thumb3"]["top"].Value, Epsilon)
-	assert.InDelta(t, 265, nodes["title2"]["top"].Value, Epsilon)
-	assert.InDelta(t, 265, nodes["title3"]["top"].Value, Epsilon)
-	assert.InDelta(t, 400, nodes["thumb4"]["top"].Value, Epsilon)
-	assert.InDelta(t, 400, nodes["thumb5"]["top"].Value, Epsilon)
-	assert.InDelta(t, 465, nodes["title4"]["top"].Value, Epsilon)
-	assert.InDelta(t, 465, nodes["title5"]["top"].Value, Epsilon)
+	assert.InDelta(t, 210, nodes["thumb2"]["top"].Value, Epsilon)
+	assert.InDelta(t, 210, nodes["thumb3"]["top"].Value, Epsilon)
+	assert.InDelta(t, 275, nodes["title2"]["top"].Value, Epsilon)
+	assert.InDelta(t, 275, nodes["title3"]["top"].Value, Epsilon)
+	assert.InDelta(t, 420, nodes["thumb4"]["top"].Value, Epsilon)
+	assert.InDelta(t, 420, nodes["thumb5"]["top"].Value, Epsilon)
+	assert.InDelta(t, 485, nodes["title4"]["top"].Value, Epsilon)
+	assert.InDelta(t, 485, nodes["title5"]["top"].Value, Epsilon)
 }
 
 // func TestGridX1000(t *testing.T) {
@@ -248,14 +165,14 @@ func TestGridLayout(t *testing.T) {
 // 	log.Printf("testGridX1000 took %s.", time.Since(start))
 // }
 
-func printNodes(variables nodesMap) {
+func printNodes(variables map[string]map[string]*Variable) {
 	for nodeName, nodes := range variables {
 		log.Printf("node: %s", nodeName)
 		printVariables(nodes)
 	}
 }
 
-func printVariables(nodes nodeMap) {
+func printVariables(nodes map[string]*Variable) {
 	for name, v := range nodes {
 
 		log.Printf(" %s = %f (address:%+v)", name, v.Value, v)
@@ -0,0 +1,791 @@
+package cassgowary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// recordingHeader starts every recording, the number is the format version.
+const recordingHeader = "cassgowary-recording 2"
+
+const replayTolerance = 1.0e-6
+
+// A Recorder wraps a Solver and writes every public call, together with
+// its result, to a line based log that a Recording can be read from.
+//
+//	cassgowary-recording 2
+//	options implicit-stays
+//	var v0 "x" 0
+//	constraint c0 v0 >= 10
+//	add c0 => ok
+//	edit v0 1e+06 => ok
+//	suggest v0 42 => ok
+//	update v0=42 => ok
+//	set v0 7
+//
+// The options line has the options of the solver. Variables are declared
+// with their value, and constraints in the syntax of the ConstraintParser
+// with the variables named v0, v1 and so on, both on first use. A set
+// line records a value written to a variable between two calls, as the
+// implicit stays of new variables start at their values.
+//
+// The groups of the solver are only recorded when they are created and
+// changed through the recorder, rather than through their own methods.
+type Recorder struct {
+	solver    *Solver
+	w         io.Writer
+	err       error
+	variables map[*Variable]int
+	order     []*Variable
+	// The values of the variables as far as the log has them.
+	values      []float64
+	constraints map[*Constraint]int
+	groups      map[*ConstraintGroup]int
+	// The number of groups declared, including those that failed to be
+	// created.
+	groupCount int
+	format     formatter
+}
+
+// NewRecorder returns a recorder writing the calls made on the solver to w.
+func NewRecorder(s *Solver, w io.Writer) *Recorder {
+	rec := &Recorder{
+		solver:      s,
+		w:           w,
+		variables:   map[*Variable]int{},
+		constraints: map[*Constraint]int{},
+		groups:      map[*ConstraintGroup]int{},
+	}
+	rec.format = formatter{name: rec.variable}
+	rec.writeLine(recordingHeader)
+	rec.writeLine(strings.Join(append([]string{"options"}, solverOptions(s)...), " "))
+	return rec
+}
+
+// Solver returns the recorded solver.
+func (rec *Recorder) Solver() *Solver {
+	return rec.solver
+}
+
+// Err returns the first error that occurred writing the log.
+func (rec *Recorder) Err() error {
+	return rec.err
+}
+
+func (rec *Recorder) AddConstraint(c *Constraint) error {
+	fields := rec.call("add", rec.constraint(c))
+	err := rec.solver.AddConstraint(c)
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) RemoveConstraint(c *Constraint) error {
+	fields := rec.call("remove", rec.constraint(c))
+	err := rec.solver.RemoveConstraint(c)
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) AddEditVariable(v *Variable, strength Strength) error {
+	fields := rec.call("edit", rec.variable(v), formatNumber(float64(strength)))
+	err := rec.solver.AddEditVariable(v, strength)
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) RemoveEditVariable(v *Variable) error {
+	fields := rec.call("unedit", rec.variable(v))
+	err := rec.solver.RemoveEditVariable(v)
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) SuggestValue(v *Variable, value float64) error {
+	fields := rec.call("suggest", rec.variable(v), formatNumber(value))
+	err := rec.solver.SuggestValue(v, value)
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) UpdateVariables() error {
+	fields := rec.call("update")
+	err := rec.solver.UpdateVariables()
+	for i, v := range rec.order {
+		rec.values[i] = v.Value
+		fields = append(fields, fmt.Sprintf("v%d=%s", i, formatNumber(v.Value)))
+	}
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) Refresh() error {
+	fields := rec.call("refresh")
+	err := rec.solver.Refresh()
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) ExerciseAmbiguity() error {
+	fields := rec.call("exercise")
+	err := rec.solver.ExerciseAmbiguity()
+	rec.writeCall(fields, err)
+	return err
+}
+
+func (rec *Recorder) NewConstraintGroup(name string, members ...*Constraint) (*ConstraintGroup, error) {
+	id := rec.declareGroup(name)
+	fields := rec.call("newgroup", "g"+strconv.Itoa(id))
+	for _, c := range members {
+		fields = append(fields, rec.constraint(c))
+	}
+
+	g, err := rec.solver.NewConstraintGroup(name, members...)
+	if g != nil {
+		rec.groups[g] = id
+	}
+	rec.writeCall(fields, err)
+	return g, err
+}
+
+func (rec *Recorder) RemoveConstraintGroup(g *ConstraintGroup) error {
+	fields := rec.call("remove-group", rec.group(g))
+	err := rec.solver.RemoveConstraintGroup(g)
+	rec.writeCall(fields, err)
+	return err
+}
+
+// UpdateGroups records the activation and deactivation of the groups,
+// written as +g0 and -g0.
+func (rec *Recorder) UpdateGroups(activate, deactivate []*ConstraintGroup) error {
+	fields := rec.call("groups")
+	for _, g := range activate {
+		fields = append(fields, "+"+rec.group(g))
+	}
+	for _, g := range deactivate {
+		fields = append(fields, "-"+rec.group(g))
+	}
+	err := rec.solver.UpdateGroups(activate, deactivate)
+	rec.writeCall(fields, err)
+	return err
+}
+
+// ActivateGroup records g.Activate.
+func (rec *Recorder) ActivateGroup(g *ConstraintGroup) error {
+	return rec.UpdateGroups([]*ConstraintGroup{g}, nil)
+}
+
+// DeactivateGroup records g.Deactivate.
+func (rec *Recorder) DeactivateGroup(g *ConstraintGroup) error {
+	return rec.UpdateGroups(nil, []*ConstraintGroup{g})
+}
+
+// AddToGroup records g.Add.
+func (rec *Recorder) AddToGroup(g *ConstraintGroup, members ...*Constraint) error {
+	fields := rec.call("group-add", rec.group(g))
+	for _, c := range members {
+		fields = append(fields, rec.constraint(c))
+	}
+	err := g.Add(members...)
+	rec.writeCall(fields, err)
+	return err
+}
+
+// RemoveFromGroup records g.Remove.
+func (rec *Recorder) RemoveFromGroup(g *ConstraintGroup, members ...*Constraint) error {
+	fields := rec.call("group-remove", rec.group(g))
+	for _, c := range members {
+		fields = append(fields, rec.constraint(c))
+	}
+	err := g.Remove(members...)
+	rec.writeCall(fields, err)
+	return err
+}
+
+// Start the fields of a call, after writing the values changed since the
+// log last had them.
+func (rec *Recorder) call(op string, args ...string) []string {
+	for i, v := range rec.order {
+		if v.Value != rec.values[i] && !(math.IsNaN(v.Value) && math.IsNaN(rec.values[i])) {
+			rec.values[i] = v.Value
+			rec.writeLine(fmt.Sprintf("set v%d %s", i, formatNumber(v.Value)))
+		}
+	}
+	return append([]string{op}, args...)
+}
+
+// Get the name of the variable in the log, declaring it on first use.
+func (rec *Recorder) variable(v *Variable) string {
+	id, exists := rec.variables[v]
+	if !exists {
+		id = len(rec.order)
+		rec.variables[v] = id
+		rec.order = append(rec.order, v)
+		rec.values = append(rec.values, v.Value)
+		rec.writeLine(fmt.Sprintf("var v%d %s %s", id, strconv.Quote(v.Name), formatNumber(v.Value)))
+	}
+	return "v" + strconv.Itoa(id)
+}
+
+// Get the name of the constraint in the log, declaring it on first use.
+func (rec *Recorder) constraint(c *Constraint) string {
+	id, exists := rec.constraints[c]
+	if !exists {
+		id = len(rec.constraints)
+		rec.constraints[c] = id
+		rec.writeLine(fmt.Sprintf("constraint c%d %s", id, rec.format.constraint(c)))
+	}
+	return "c" + strconv.Itoa(id)
+}
+
+// Get the name of the group in the log. A group which wasn't created
+// through the recorder is declared on first use, and is missing from the
+// solver on replay.
+func (rec *Recorder) group(g *ConstraintGroup) string {
+	id, exists := rec.groups[g]
+	if !exists {
+		id = rec.declareGroup(g.Name)
+		rec.groups[g] = id
+	}
+	return "g" + strconv.Itoa(id)
+}
+
+// Declare a group in the log and return its id.
+func (rec *Recorder) declareGroup(name string) int {
+	id := rec.groupCount
+	rec.groupCount++
+	rec.writeLine(fmt.Sprintf("group g%d %s", id, strconv.Quote(name)))
+	return id
+}
+
+func (rec *Recorder) writeCall(fields []string, err error) {
+	fields = append(fields, "=>", errorClass(err))
+	rec.writeLine(strings.Join(fields, " "))
+}
+
+func (rec *Recorder) writeLine(line string) {
+	if rec.err != nil {
+		return
+	}
+	_, rec.err = io.WriteString(rec.w, line+"\n")
+}
+
+// Get the options of the solver as the options line has them.
+func solverOptions(s *Solver) []string {
+	var options []string
+	if s.implicitStays {
+		options = append(options, "implicit-stays")
+	}
+	if s.presolve {
+		options = append(options, "presolve")
+	}
+	if s.recovery {
+		options = append(options, "recovery:"+formatNumber(float64(s.recoveryStrength)))
+	}
+	if s.refreshPivots != 0 || s.refreshTolerance != 0 {
+		options = append(options, fmt.Sprintf("auto-refresh:%d:%s", s.refreshPivots, formatNumber(s.refreshTolerance)))
+	}
+	return options
+}
+
+// Get the solver options of the fields of an options line.
+func parseSolverOptions(fields []string) ([]SolverOption, error) {
+	var options []SolverOption
+	for _, field := range fields {
+		parts := strings.Split(field, ":")
+		switch {
+		case field == "implicit-stays":
+			options = append(options, WithImplicitStays())
+		case field == "presolve":
+			options = append(options, WithPresolve())
+		case parts[0] == "recovery" && len(parts) == 2:
+			strength, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad option '%s'", field)
+			}
+			options = append(options, WithRecovery(Strength(strength)))
+		case parts[0] == "auto-refresh" && len(parts) == 3:
+			pivots, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad option '%s'", field)
+			}
+			tolerance, err := strconv.ParseFloat(parts[2], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad option '%s'", field)
+			}
+			options = append(options, WithAutoRefresh(pivots, tolerance))
+		default:
+			return nil, fmt.Errorf("unknown option '%s'", field)
+		}
+	}
+	return options, nil
+}
+
+// Get the class of the error written to the log. Messages aren't
+// compared on replay since they contain variable values.
+func errorClass(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	switch cause := errors.Cause(err); cause {
+	case DuplicateEditVariableErr:
+		return "duplicate-edit"
+	case UnknownEditVariableErr:
+		return "unknown-edit"
+	case RequiredFailureErr:
+		return "required"
+	case NonConvexExpressionErr:
+		return "non-convex"
+	default:
+		if ce, ok := cause.(*ConstraintError); ok {
+			return strings.Replace(ce.Reason, " ", "-", -1)
+		}
+		return "error"
+	}
+}
+
+// RecordedCall is a single call read from a recording.
+type RecordedCall struct {
+	// Line is the line of the call in the recording.
+	Line int
+	// Op is one of add, remove, edit, unedit, suggest, update, set,
+	// refresh, exercise, newgroup, remove-group, groups, group-add or
+	// group-remove.
+	Op string
+	// Fields are the arguments of the call.
+	Fields []string
+	// Result is the recorded error class, empty for set.
+	Result string
+}
+
+func (call RecordedCall) String() string {
+	line := strings.Join(append([]string{call.Op}, call.Fields...), " ")
+	if call.Result != "" {
+		line += " => " + call.Result
+	}
+	return line
+}
+
+// A Recording is the call sequence read from a Recorder log, along with
+// the options of the solver and what the calls refer to by number.
+type Recording struct {
+	Options []string
+	// Variables are the names of the variables, and Values the values
+	// they were declared with.
+	Variables []string
+	Values    []float64
+	// Constraints are written in the syntax of the ConstraintParser,
+	// with the variables named v0, v1 and so on.
+	Constraints []string
+	// Groups are the names of the constraint groups.
+	Groups []string
+	Calls  []RecordedCall
+}
+
+// ReadRecording reads the log written by a Recorder.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, errors.New("empty recording")
+	}
+	if header := strings.TrimSpace(scanner.Text()); header != recordingHeader {
+		return nil, fmt.Errorf("unsupported recording header '%s'", header)
+	}
+
+	rec := &Recording{}
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		declared := func(prefix string, count int) (string, error) {
+			if len(fields) < 3 || fields[1] != fmt.Sprintf("%s%d", prefix, count) {
+				return "", fmt.Errorf("line %d: bad %s declaration", line, fields[0])
+			}
+			return strings.TrimSpace(strings.SplitN(text, " ", 3)[2]), nil
+		}
+		switch fields[0] {
+		case "options":
+			rec.Options = fields[1:]
+			continue
+
+		case "var":
+			rest, err := declared("v", len(rec.Variables))
+			if err != nil {
+				return nil, err
+			}
+			end := strings.LastIndex(rest, " ")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: bad var declaration", line)
+			}
+			name, err := strconv.Unquote(rest[:end])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: bad variable name", line)
+			}
+			value, err := strconv.ParseFloat(rest[end+1:], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: bad variable value", line)
+			}
+			rec.Variables = append(rec.Variables, name)
+			rec.Values = append(rec.Values, value)
+			continue
+
+		case "constraint":
+			rest, err := declared("c", len(rec.Constraints))
+			if err != nil {
+				return nil, err
+			}
+			rec.Constraints = append(rec.Constraints, rest)
+			continue
+
+		case "group":
+			rest, err := declared("g", len(rec.Groups))
+			if err != nil {
+				return nil, err
+			}
+			name, err := strconv.Unquote(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: bad group name", line)
+			}
+			rec.Groups = append(rec.Groups, name)
+			continue
+		}
+
+		call := RecordedCall{
+			Line: line,
+			Op:   fields[0],
+		}
+		args := fields[1:]
+		if n := len(args); n >= 2 && args[n-2] == "=>" {
+			call.Result = args[n-1]
+			args = args[:n-2]
+		}
+		call.Fields = args
+		rec.Calls = append(rec.Calls, call)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "can't read recording")
+	}
+	return rec, nil
+}
+
+// WriteTo writes the recording in the Recorder log format, with the
+// declarations ahead of the calls.
+func (rec *Recording) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	sb.WriteString(recordingHeader + "\n")
+	sb.WriteString(strings.Join(append([]string{"options"}, rec.Options...), " ") + "\n")
+	for i, name := range rec.Variables {
+		sb.WriteString(fmt.Sprintf("var v%d %s %s\n", i, strconv.Quote(name), formatNumber(rec.Values[i])))
+	}
+	for i, text := range rec.Constraints {
+		sb.WriteString(fmt.Sprintf("constraint c%d %s\n", i, text))
+	}
+	for i, name := range rec.Groups {
+		sb.WriteString(fmt.Sprintf("group g%d %s\n", i, strconv.Quote(name)))
+	}
+	for _, call := range rec.Calls {
+		sb.WriteString(call.String() + "\n")
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// ReplayMismatchError is returned by Replay when a call doesn't behave
+// the way it did when it was recorded.
+type ReplayMismatchError struct {
+	Call     RecordedCall
+	Expected string
+	Actual   string
+}
+
+func (e *ReplayMismatchError) Error() string {
+	return fmt.Sprintf(
+		"line %d: '%s' expected %s, got %s",
+		e.Call.Line, e.Call, e.Expected, e.Actual,
+	)
+}
+
+// Replay makes the recorded calls on the solver, which should be fresh,
+// and checks every result against the recording. The options of the
+// recording are set on the solver first. The first difference is
+// returned as a *ReplayMismatchError.
+func (rec *Recording) Replay(s *Solver) error {
+	options, err := parseSolverOptions(rec.Options)
+	if err != nil {
+		return err
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	variables := make([]*Variable, len(rec.Variables))
+	resolver := StrictResolver{}
+	for i, name := range rec.Variables {
+		variables[i] = NewVariable(name)
+		variables[i].Value = rec.Values[i]
+		resolver[fmt.Sprintf("v%d", i)] = variables[i]
+	}
+	constraints := make([]*Constraint, len(rec.Constraints))
+	for i, text := range rec.Constraints {
+		c, err := parseRecordedConstraint(text, resolver)
+		if err != nil {
+			return errors.Wrapf(err, "bad constraint c%d", i)
+		}
+		constraints[i] = c
+	}
+	groups := make([]*ConstraintGroup, len(rec.Groups))
+
+	// Get the index of an object the call refers to, like v3 or c0.
+	index := func(call RecordedCall, name, prefix string, count int) (int, error) {
+		id, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+		if err != nil || !strings.HasPrefix(name, prefix) || id < 0 || id >= count {
+			return 0, fmt.Errorf("line %d: unknown name '%s'", call.Line, name)
+		}
+		return id, nil
+	}
+	variable := func(call RecordedCall, name string) (*Variable, error) {
+		id, err := index(call, name, "v", len(variables))
+		if err != nil {
+			return nil, err
+		}
+		return variables[id], nil
+	}
+	constraintList := func(call RecordedCall, names []string) ([]*Constraint, error) {
+		cns := make([]*Constraint, len(names))
+		for i, name := range names {
+			id, err := index(call, name, "c", len(constraints))
+			if err != nil {
+				return nil, err
+			}
+			cns[i] = constraints[id]
+		}
+		return cns, nil
+	}
+	group := func(call RecordedCall, name string) (*ConstraintGroup, error) {
+		id, err := index(call, name, "g", len(groups))
+		if err != nil {
+			return nil, err
+		}
+		if groups[id] == nil {
+			return nil, fmt.Errorf("line %d: group '%s' wasn't created", call.Line, name)
+		}
+		return groups[id], nil
+	}
+	arguments := func(call RecordedCall, min, max int) error {
+		if len(call.Fields) < min || max >= 0 && len(call.Fields) > max {
+			return fmt.Errorf("line %d: bad %s", call.Line, call.Op)
+		}
+		return nil
+	}
+
+	for _, call := range rec.Calls {
+		var err error
+		switch call.Op {
+		case "add", "remove":
+			if aerr := arguments(call, 1, 1); aerr != nil {
+				return aerr
+			}
+			cns, cerr := constraintList(call, call.Fields)
+			if cerr != nil {
+				return cerr
+			}
+			if call.Op == "add" {
+				err = s.AddConstraint(cns[0])
+			} else {
+				err = s.RemoveConstraint(cns[0])
+			}
+
+		case "edit", "suggest", "set":
+			if aerr := arguments(call, 2, 2); aerr != nil {
+				return aerr
+			}
+			v, verr := variable(call, call.Fields[0])
+			if verr != nil {
+				return verr
+			}
+			f, ferr := strconv.ParseFloat(call.Fields[1], 64)
+			if ferr != nil {
+				return errors.Wrapf(ferr, "line %d", call.Line)
+			}
+			switch call.Op {
+			case "edit":
+				err = s.AddEditVariable(v, Strength(f))
+			case "suggest":
+				err = s.SuggestValue(v, f)
+			default:
+				v.Value = f
+				continue
+			}
+
+		case "unedit":
+			if aerr := arguments(call, 1, 1); aerr != nil {
+				return aerr
+			}
+			v, verr := variable(call, call.Fields[0])
+			if verr != nil {
+				return verr
+			}
+			err = s.RemoveEditVariable(v)
+
+		case "update":
+			err = s.UpdateVariables()
+			for _, field := range call.Fields {
+				parts := strings.SplitN(field, "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("line %d: bad value '%s'", call.Line, field)
+				}
+				v, verr := variable(call, parts[0])
+				if verr != nil {
+					return verr
+				}
+				expected, ferr := strconv.ParseFloat(parts[1], 64)
+				if ferr != nil {
+					return errors.Wrapf(ferr, "line %d", call.Line)
+				}
+				if math.Abs(expected-v.Value) > replayTolerance*math.Max(1, math.Abs(expected)) {
+					return &ReplayMismatchError{
+						Call:     call,
+						Expected: fmt.Sprintf("%s=%s", parts[0], parts[1]),
+						Actual:   fmt.Sprintf("%s=%s", parts[0], formatNumber(v.Value)),
+					}
+				}
+			}
+
+		case "refresh", "exercise":
+			if aerr := arguments(call, 0, 0); aerr != nil {
+				return aerr
+			}
+			if call.Op == "refresh" {
+				err = s.Refresh()
+			} else {
+				err = s.ExerciseAmbiguity()
+			}
+
+		case "newgroup":
+			if aerr := arguments(call, 1, -1); aerr != nil {
+				return aerr
+			}
+			id, ierr := index(call, call.Fields[0], "g", len(groups))
+			if ierr != nil {
+				return ierr
+			}
+			members, cerr := constraintList(call, call.Fields[1:])
+			if cerr != nil {
+				return cerr
+			}
+			groups[id], err = s.NewConstraintGroup(rec.Groups[id], members...)
+
+		case "remove-group":
+			if aerr := arguments(call, 1, 1); aerr != nil {
+				return aerr
+			}
+			g, gerr := group(call, call.Fields[0])
+			if gerr != nil {
+				return gerr
+			}
+			err = s.RemoveConstraintGroup(g)
+
+		case "groups":
+			var activate, deactivate []*ConstraintGroup
+			for _, field := range call.Fields {
+				g, gerr := group(call, strings.TrimLeft(field, "+-"))
+				if gerr != nil {
+					return gerr
+				}
+				if strings.HasPrefix(field, "+") {
+					activate = append(activate, g)
+				} else {
+					deactivate = append(deactivate, g)
+				}
+			}
+			err = s.UpdateGroups(activate, deactivate)
+
+		case "group-add", "group-remove":
+			if aerr := arguments(call, 1, -1); aerr != nil {
+				return aerr
+			}
+			g, gerr := group(call, call.Fields[0])
+			if gerr != nil {
+				return gerr
+			}
+			members, cerr := constraintList(call, call.Fields[1:])
+			if cerr != nil {
+				return cerr
+			}
+			if call.Op == "group-add" {
+				err = g.Add(members...)
+			} else {
+				err = g.Remove(members...)
+			}
+
+		default:
+			return fmt.Errorf("line %d: unknown call '%s'", call.Line, call.Op)
+		}
+
+		if actual := errorClass(err); actual != call.Result {
+			return &ReplayMismatchError{
+				Call:     call,
+				Expected: call.Result,
+				Actual:   actual,
+			}
+		}
+	}
+	return nil
+}
+
+// Parse a constraint of a recording. Min, Max and Abs are left for the
+// solver to reject, as it did when the constraint was recorded.
+func parseRecordedConstraint(text string, resolver VariableResolver) (*Constraint, error) {
+	p, err := newParser(text, resolver)
+	if err != nil {
+		return nil, err
+	}
+	p.unchecked = true
+	return p.constraint()
+}
+
+// Reduce removes calls from the recording as long as the failure check
+// keeps reporting true, and returns the smallest recording found. Use it
+// to cut a field bug report down to the calls that matter.
+func (rec *Recording) Reduce(fails func(*Recording) bool) *Recording {
+	current := *rec
+	current.Calls = append([]RecordedCall{}, rec.Calls...)
+
+	for chunk := len(current.Calls) / 2; chunk >= 1; {
+		reduced := false
+		for start := 0; start < len(current.Calls); {
+			end := start + chunk
+			if end > len(current.Calls) {
+				end = len(current.Calls)
+			}
+
+			calls := make([]RecordedCall, 0, len(current.Calls)-(end-start))
+			calls = append(calls, current.Calls[:start]...)
+			calls = append(calls, current.Calls[end:]...)
+			candidate := current
+			candidate.Calls = calls
+
+			if fails(&candidate) {
+				current = candidate
+				reduced = true
+			} else {
+				start = end
+			}
+		}
+
+		// Single calls are retried until nothing more can be removed,
+		// since dropping one may make an earlier one unnecessary.
+		if chunk > 1 || !reduced {
+			chunk /= 2
+		}
+	}
+	return &current
+}
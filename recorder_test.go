@@ -0,0 +1,144 @@
+package cassgowary
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordSession(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	rec := NewRecorder(NewSolver(), &buf)
+	x := NewVariable("x")
+	y := NewVariable("y y")
+	z := NewVariable("z")
+
+	cx := x.GreaterThanOrEqualToFloat(10)
+	assert.NoError(t, rec.AddConstraint(cx))
+	assert.NoError(t, rec.AddConstraint(y.EqualsExpression(x.Multiply(2).AddFloat(0.1))))
+	assert.NoError(t, rec.AddConstraint(z.EqualsFloat(3)))
+	assert.Error(t, rec.AddConstraint(z.EqualsFloat(5)))
+	assert.NoError(t, rec.AddEditVariable(x, Strong))
+	assert.NoError(t, rec.SuggestValue(x, 42))
+	rec.UpdateVariables()
+	assert.Error(t, rec.SuggestValue(z, 1))
+	assert.NoError(t, rec.RemoveConstraint(cx))
+	assert.NoError(t, rec.RemoveEditVariable(x))
+	rec.UpdateVariables()
+	assert.NoError(t, rec.Err())
+	return &buf
+}
+
+func TestRecorderReplay(t *testing.T) {
+	buf := recordSession(t)
+	assert.True(t, strings.HasPrefix(buf.String(), "cassgowary-recording 2\noptions\n"))
+	assert.Contains(t, buf.String(), `var v1 "y y" 0`)
+	assert.Contains(t, buf.String(), "constraint c1 2*v0 - v1 == -0.1\n")
+	assert.Contains(t, buf.String(), "=> unsatisfiable-constraint")
+
+	recording, err := ReadRecording(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y y", "z"}, recording.Variables)
+	assert.Len(t, recording.Calls, 11)
+	assert.NoError(t, recording.Replay(NewSolver()))
+
+	var out bytes.Buffer
+	_, err = recording.WriteTo(&out)
+	assert.NoError(t, err)
+	reread, err := ReadRecording(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, recording.Variables, reread.Variables)
+	assert.Len(t, reread.Calls, len(recording.Calls))
+	assert.NoError(t, reread.Replay(NewSolver()))
+}
+
+func TestRecorderReplayMismatch(t *testing.T) {
+	buf := recordSession(t)
+	tampered := strings.Replace(buf.String(), "v0=42", "v0=41", 1)
+
+	recording, err := ReadRecording(strings.NewReader(tampered))
+	assert.NoError(t, err)
+	err = recording.Replay(NewSolver())
+	if assert.IsType(t, &ReplayMismatchError{}, err) {
+		mismatch := err.(*ReplayMismatchError)
+		assert.Equal(t, "update", mismatch.Call.Op)
+		assert.Equal(t, "v0=41", mismatch.Expected)
+		assert.Equal(t, "v0=42", mismatch.Actual)
+	}
+
+	_, err = ReadRecording(strings.NewReader("cassgowary-recording 1\n"))
+	assert.Error(t, err)
+}
+
+func TestRecordingReduce(t *testing.T) {
+	recording, err := ReadRecording(recordSession(t))
+	assert.NoError(t, err)
+
+	reduced := recording.Reduce(func(r *Recording) bool {
+		if r.Replay(NewSolver()) != nil {
+			return false
+		}
+		for _, call := range r.Calls {
+			if call.Result == "unsatisfiable-constraint" {
+				return true
+			}
+		}
+		return false
+	})
+
+	if assert.Len(t, reduced.Calls, 2) {
+		assert.Equal(t, "add c2 => ok", reduced.Calls[0].String())
+		assert.Equal(t, "unsatisfiable-constraint", reduced.Calls[1].Result)
+	}
+}
+
+func TestRecorderOptionsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(NewSolver(WithImplicitStays(), WithRecovery(Medium), WithAutoRefresh(50, 1e-9)), &buf)
+	width, height, left := NewVariable("width"), NewVariable("height"), NewVariable("left")
+
+	portrait, err := rec.NewConstraintGroup("portrait", width.EqualsFloat(300), height.EqualsFloat(500))
+	assert.NoError(t, err)
+	landscape, err := rec.NewConstraintGroup("landscape", width.EqualsFloat(500))
+	assert.NoError(t, err)
+	assert.NoError(t, rec.AddToGroup(landscape, height.EqualsFloat(300), left.GreaterThanOrEqualToFloat(0)))
+	_, err = rec.NewConstraintGroup("portrait")
+	assert.Error(t, err)
+
+	assert.NoError(t, rec.ActivateGroup(portrait))
+	assert.NoError(t, rec.UpdateGroups([]*ConstraintGroup{landscape}, []*ConstraintGroup{portrait}))
+	assert.NoError(t, rec.RemoveFromGroup(portrait, portrait.Members()[1]))
+	assert.NoError(t, rec.DeactivateGroup(landscape))
+	assert.NoError(t, rec.RemoveConstraintGroup(portrait))
+
+	// The implicit stay of left starts at the value it has when it's
+	// added again.
+	left.Value = 20
+	assert.NoError(t, rec.AddConstraint(left.GreaterThanOrEqualToFloat(0)))
+	assert.NoError(t, rec.Refresh())
+	assert.NoError(t, rec.ExerciseAmbiguity())
+	assert.Error(t, rec.AddConstraint(left.LessThanOrEqualToExpression(Max(height.AddFloat(0), width.AddFloat(0)))))
+	rec.UpdateVariables()
+	assert.InDelta(t, 20, left.Value, Epsilon)
+	assert.NoError(t, rec.Err())
+
+	log := buf.String()
+	assert.Contains(t, log, "options implicit-stays recovery:1000 auto-refresh:50:1e-09\n")
+	assert.Contains(t, log, `group g1 "landscape"`)
+	assert.Contains(t, log, "groups +g1 -g0 => ok\n")
+	assert.Contains(t, log, "set v2 20\n")
+	assert.Contains(t, log, "=> non-convex\n")
+
+	recording, err := ReadRecording(strings.NewReader(log))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"portrait", "landscape", "portrait"}, recording.Groups)
+	assert.NoError(t, recording.Replay(NewSolver()))
+
+	// Without the implicit stays left doesn't stay at 20.
+	recording.Options = nil
+	assert.IsType(t, &ReplayMismatchError{}, recording.Replay(NewSolver()))
+}
@@ -0,0 +1,46 @@
+package cassgowary
+
+// Warning describes a problem the solver recovered from.
+type Warning struct {
+	// Constraint is the constraint as it was passed to the solver.
+	Constraint *Constraint
+	// Strength is the strength the constraint was added with instead.
+	Strength Strength
+	// Err is the error the constraint would have failed with.
+	Err error
+}
+
+func (w Warning) String() string {
+	return "will attempt to recover by breaking constraint: " + w.Err.Error()
+}
+
+// BrokenConstraints returns the required constraints the solver
+// could not satisfy and added with a lower strength instead.
+func (s *Solver) BrokenConstraints() []*Constraint {
+	broken := make([]*Constraint, 0, s.broken.Size())
+	s.broken.Each(func(k, _ interface{}) {
+		broken = append(broken, k.(*Constraint))
+	})
+	return broken
+}
+
+// Add a copy of the conflicting required constraint with the recovery
+// strength. The copy is what goes into the tableau, the original is kept
+// as the handle the caller removes it with. The failed attempt must have
+// restored the tableau.
+func (s *Solver) breakConstraint(c *Constraint, cause error) error {
+	replacement := NewConstraintFrom(c, s.recoveryStrength)
+	if err := s.addConstraint(replacement, true); err != nil {
+		return err
+	}
+	s.broken.Put(c, replacement)
+
+	if s.warn != nil {
+		s.warn(Warning{
+			Constraint: c,
+			Strength:   s.recoveryStrength,
+			Err:        cause,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryBreaksConflictingConstraint(t *testing.T) {
+	warnings := []Warning{}
+	solver := NewSolver(
+		WithRecovery(AlmostRequired),
+		WithWarningHandler(func(w Warning) {
+			warnings = append(warnings, w)
+		}),
+	)
+	x := NewVariable("x")
+
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(10)))
+	conflict := x.LessThanOrEqualToFloat(5)
+	assert.NoError(t, solver.AddConstraint(conflict))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.NoError(t, solver.Validate(1e-6))
+
+	assert.Equal(t, []*Constraint{conflict}, solver.BrokenConstraints())
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, conflict, warnings[0].Constraint)
+		assert.Equal(t, AlmostRequired, warnings[0].Strength)
+		assert.True(t, IsUnsatisfiable(warnings[0].Err))
+	}
+
+	assert.Error(t, solver.AddConstraint(conflict))
+
+	assert.NoError(t, solver.RemoveConstraint(conflict))
+	assert.Empty(t, solver.BrokenConstraints())
+	assert.NoError(t, solver.AddConstraint(x.LessThanOrEqualToFloat(20)))
+}
+
+func TestRecoveryPrefersRequired(t *testing.T) {
+	solver := NewSolver(WithRecovery(AlmostRequired))
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(10)))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(5))))
+	assert.NoError(t, solver.AddConstraint(y.EqualsFloat(100)))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(20).NewModifyStrength(Strong)))
+	solver.UpdateVariables()
+
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.InDelta(t, 15, y.Value, Epsilon)
+	assert.Len(t, solver.BrokenConstraints(), 1)
+}
+
+func TestWithoutRecovery(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(10)))
+	err := solver.AddConstraint(x.EqualsFloat(5))
+	assert.True(t, IsUnsatisfiable(err))
+	assert.Empty(t, solver.BrokenConstraints())
+}
+
+func TestRecoveryFromArtificialFailure(t *testing.T) {
+	solver := NewSolver(WithRecovery(Strong))
+	a, b := NewVariable("a"), NewVariable("b")
+	for _, v := range []*Variable{a, b} {
+		assert.NoError(t, solver.AddConstraint(NewConstraint(variableExpression(v), OP_EQ, Weak)))
+	}
+
+	assert.NoError(t, solver.AddConstraint(a.Add(b).LessThanOrEqualToFloat(-11)))
+	assert.NoError(t, solver.AddConstraint(b.GreaterThanOrEqualToFloat(0)))
+	conflict := a.EqualsFloat(-6)
+	assert.NoError(t, solver.AddConstraint(conflict))
+	solver.UpdateVariables()
+
+	assert.Equal(t, []*Constraint{conflict}, solver.BrokenConstraints())
+	assert.InDelta(t, -11, a.Value, Epsilon)
+	assert.InDelta(t, 0, b.Value, Epsilon)
+	assert.NoError(t, solver.Validate(1e-6))
+}
@@ -0,0 +1,103 @@
+package cassgowary
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Residue is the amount by which a required constraint is violated
+// by the values in the current tableau.
+type Residue struct {
+	Constraint *Constraint
+	Value      float64
+}
+
+// ValidationError is returned by Validate for residues above the tolerance.
+type ValidationError struct {
+	Residues []Residue
+}
+
+func (e *ValidationError) Error() string {
+	info := make([]string, len(e.Residues))
+	for i, r := range e.Residues {
+		info[i] = fmt.Sprintf("%g (%s)", r.Value, r.Constraint)
+	}
+	return "constraint residues above tolerance: " + strings.Join(info, ", ")
+}
+
+// Refresh rebuilds the tableau from scratch from the registered constraints
+// and the current edit constants. Long sessions of edits and add/remove
+// cycles leave small residues in the rows, a refresh gets rid of them.
+// Edit variables, stays, groups and broken constraints all survive it.
+func (s *Solver) Refresh() error {
+	var err error
+	s.components.Each(func(_ int, v interface{}) {
+		if rerr := s.rebuild(v.(*component)); rerr != nil && err == nil {
+			err = errors.Wrap(rerr, "can't refresh")
+		}
+	})
+	s.pivots = 0
+	return err
+}
+
+// Validate checks every required constraint against the values in the
+// current tableau. Constraints violated by more than the tolerance are
+// returned in a *ValidationError.
+func (s *Solver) Validate(tolerance float64) error {
+	var residues []Residue
+	s.cns.Each(func(k, v interface{}) {
+		c, t := k.(*Constraint), v.(*tag)
+		if c.Strength < Required {
+			return
+		}
+
+		e := s.resolved(c).expression
+		value := e.Constant
+		for _, term := range e.Terms {
+			sym, exists := s.vars.Get(term.Variable)
+			if !exists {
+				continue
+			}
+			if r, exists := t.owner.rows.Get(sym.(*symbol)); exists {
+				value += term.Coefficient * r.constant
+			}
+		}
+
+		var residue float64
+		switch c.Op {
+		case OP_EQ:
+			residue = math.Abs(value)
+		case OP_LE:
+			residue = value
+		case OP_GE:
+			residue = -value
+		case OP_RANGE:
+			residue = math.Max(-value, value-c.span)
+		}
+		if residue > tolerance {
+			residues = append(residues, Residue{
+				Constraint: c,
+				Value:      residue,
+			})
+		}
+	})
+
+	if len(residues) > 0 {
+		return &ValidationError{Residues: residues}
+	}
+	return nil
+}
+
+// Refresh the tableau when the limits set by WithAutoRefresh are reached.
+func (s *Solver) autoRefresh() error {
+	switch {
+	case s.refreshPivots > 0 && s.pivots >= s.refreshPivots:
+	case s.refreshTolerance > 0 && s.Validate(s.refreshTolerance) != nil:
+	default:
+		return nil
+	}
+	return s.Refresh()
+}
@@ -0,0 +1,90 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshKeepsSolution(t *testing.T) {
+	solver := NewSolver(WithImplicitStays())
+	left := NewVariable("left")
+	width := NewVariable("width")
+	right := NewVariable("right")
+
+	assert.NoError(t, solver.AddConstraint(right.EqualsExpression(left.Add(width))))
+	assert.NoError(t, solver.AddConstraint(width.GreaterThanOrEqualToFloat(50)))
+	g, err := solver.NewConstraintGroup("wide", width.EqualsFloat(200).NewModifyStrength(Strong))
+	assert.NoError(t, err)
+	assert.NoError(t, g.Activate())
+	assert.NoError(t, solver.AddEditVariable(left, Medium))
+	assert.NoError(t, solver.SuggestValue(left, 30))
+	solver.UpdateVariables()
+	assert.InDelta(t, 230, right.Value, Epsilon)
+
+	assert.NoError(t, solver.Refresh())
+	solver.UpdateVariables()
+	assert.InDelta(t, 30, left.Value, Epsilon)
+	assert.InDelta(t, 200, width.Value, Epsilon)
+	assert.InDelta(t, 230, right.Value, Epsilon)
+
+	assert.NoError(t, solver.SuggestValue(left, 40))
+	assert.NoError(t, g.Deactivate())
+	solver.UpdateVariables()
+	assert.InDelta(t, 40, left.Value, Epsilon)
+	assert.InDelta(t, 200, width.Value, Epsilon)
+	assert.InDelta(t, 240, right.Value, Epsilon)
+}
+
+func TestValidateFindsResidues(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	cy := y.EqualsExpression(x.AddFloat(10))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(5)))
+	assert.NoError(t, solver.AddConstraint(cy))
+	assert.NoError(t, solver.Validate(1e-9))
+
+	owner, _ := solver.owners.Get(y)
+	r, _ := owner.(*component).rows.Get(solver.varSymbol(y))
+	r.constant += 1e-6
+
+	err := solver.Validate(1e-9)
+	if assert.IsType(t, &ValidationError{}, err) {
+		residues := err.(*ValidationError).Residues
+		if assert.Len(t, residues, 1) {
+			assert.Equal(t, cy, residues[0].Constraint)
+			assert.InDelta(t, 1e-6, residues[0].Value, 1e-9)
+		}
+	}
+
+	assert.NoError(t, solver.Refresh())
+	assert.NoError(t, solver.Validate(1e-9))
+	solver.UpdateVariables()
+	assert.InDelta(t, 15, y.Value, Epsilon)
+}
+
+func TestAutoRefreshAfterPivots(t *testing.T) {
+	tracer := NewRecordingTracer()
+	solver := NewSolver(WithTracer(tracer), WithAutoRefresh(4, 0))
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(0)))
+	assert.NoError(t, solver.AddConstraint(x.LessThanOrEqualToFloat(100)))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(NewExpressionFrom(x.Multiply(2)))))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+
+	rows := tracer.Count(TraceRowCreated)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, solver.SuggestValue(x, float64(i%2)*200-50))
+	}
+	assert.True(t, tracer.Count(TraceRowCreated) > rows)
+	assert.True(t, solver.pivots < 4)
+
+	assert.NoError(t, solver.SuggestValue(x, 60))
+	solver.UpdateVariables()
+	assert.InDelta(t, 60, x.Value, Epsilon)
+	assert.InDelta(t, 120, y.Value, Epsilon)
+}
@@ -6,16 +6,21 @@ const (
 	OP_LE RelationalOperator = iota
 	OP_GE
 	OP_EQ
+	// OP_RANGE keeps the expression between 0 and the span of the
+	// constraint, see Between.
+	OP_RANGE
 )
 
 var OperationNames = map[RelationalOperator]string{
-	OP_LE: "LEQ",
-	OP_GE: "GEQ",
-	OP_EQ: "EQ",
+	OP_LE:    "LEQ",
+	OP_GE:    "GEQ",
+	OP_EQ:    "EQ",
+	OP_RANGE: "RANGE",
 }
 
 var OperationFromString = map[string]RelationalOperator{
-	"LEQ": OP_LE,
-	"GEQ": OP_GE,
-	"EQ":  OP_EQ,
+	"LEQ":   OP_LE,
+	"GEQ":   OP_GE,
+	"EQ":    OP_EQ,
+	"RANGE": OP_RANGE,
 }
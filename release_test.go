@@ -0,0 +1,137 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Check that no row of the solver mentions the symbol.
+func assertSymbolGone(t *testing.T, solver *Solver, sym *symbol) {
+	solver.components.Each(func(_ int, v interface{}) {
+		cp := v.(*component)
+		_, basic := cp.rows.Get(sym)
+		assert.False(t, basic)
+		assert.Empty(t, cp.columns.rowsWith(sym))
+		assert.Zero(t, cp.objective.coefficientFor(sym))
+	})
+}
+
+func TestReleaseVariableWithLastConstraint(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	cx := x.EqualsFloat(10)
+	cy := y.EqualsExpression(x.AddFloat(5))
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(cy))
+	assert.Equal(t, 2, solver.vars.Size())
+
+	assert.NoError(t, solver.RemoveConstraint(cy))
+	assert.Equal(t, 1, solver.vars.Size())
+	_, exists := solver.vars.Get(y)
+	assert.False(t, exists)
+
+	y.Value = 42
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.Equal(t, float64(42), y.Value)
+
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	assert.Equal(t, 0, solver.vars.Size())
+	assert.Empty(t, solver.refs)
+}
+
+func TestReleaseParametricVariable(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	y := NewVariable("y")
+	z := NewVariable("z")
+
+	// Written so that x and z become basic, leaving y parametric.
+	cx := NewExpression(0, NewTermFrom(x), y.Multiply(-1)).EqualsFloat(0)
+	cz := NewExpression(-5, NewTermFrom(z), y.Multiply(-1)).EqualsFloat(0)
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(cz))
+	assert.NoError(t, solver.AddConstraint(z.EqualsExpression(x.AddFloat(5))))
+
+	ySymbol := solver.varSymbol(y)
+	x0, _ := solver.owners.Get(y)
+	assert.NotEmpty(t, x0.(*component).columns.rowsWith(ySymbol))
+
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	assert.NoError(t, solver.RemoveConstraint(cz))
+	assertSymbolGone(t, solver, ySymbol)
+	assertColumnsConsistent(t, solver)
+
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 30))
+	solver.UpdateVariables()
+	assert.InDelta(t, 30, x.Value, Epsilon)
+	assert.InDelta(t, 35, z.Value, Epsilon)
+}
+
+func TestReleaseVariableKeptByEdit(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	c := x.GreaterThanOrEqualToFloat(10)
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, 1, solver.vars.Size())
+
+	assert.NoError(t, solver.SuggestValue(x, 3))
+	solver.UpdateVariables()
+	assert.InDelta(t, 3, x.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveEditVariable(x))
+	assert.Equal(t, 0, solver.vars.Size())
+}
+
+func TestReleaseVariableDropsStay(t *testing.T) {
+	solver := NewSolver(WithImplicitStays())
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	c := y.EqualsExpression(x.AddFloat(5))
+	assert.NoError(t, solver.AddConstraint(c))
+	assert.Equal(t, 2, solver.stays.Size())
+
+	assert.NoError(t, solver.RemoveConstraint(c))
+	assert.Equal(t, 0, solver.stays.Size())
+	assert.Equal(t, 0, solver.cns.Size())
+	assert.Equal(t, 0, solver.vars.Size())
+	assert.Equal(t, 0, solver.components.Size())
+}
+
+func TestReleaseVariablesDoesNotGrow(t *testing.T) {
+	solver := NewSolver(WithImplicitStays())
+	root := NewVariable("root")
+	assert.NoError(t, solver.AddConstraint(root.EqualsFloat(0)))
+
+	for i := 0; i < 100; i++ {
+		left := NewVariable("left")
+		width := NewVariable("width")
+		view := []*Constraint{
+			left.GreaterThanOrEqualToExpression(root.AddFloat(10)),
+			width.EqualsFloat(100).NewModifyStrength(Medium),
+		}
+		for _, c := range view {
+			assert.NoError(t, solver.AddConstraint(c))
+		}
+		assert.NoError(t, solver.AddEditVariable(left, Strong))
+		assert.NoError(t, solver.SuggestValue(left, float64(i)))
+		for _, c := range view {
+			assert.NoError(t, solver.RemoveConstraint(c))
+		}
+		assert.NoError(t, solver.RemoveEditVariable(left))
+	}
+
+	assert.Equal(t, 1, solver.vars.Size())
+	assert.Equal(t, 1, solver.components.Size())
+	cp := solver.components.Values()[0].(*component)
+	assert.Equal(t, 2, cp.rows.Size())
+	assertColumnsConsistent(t, solver)
+}
@@ -0,0 +1,102 @@
+package cassgowary
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MapResolver resolves names to the variables in the map, creating a
+// variable the first time a name is used.
+type MapResolver map[string]*Variable
+
+func (r MapResolver) ResolveVariable(name string) (*Variable, error) {
+	if v, exists := r[name]; exists {
+		return v, nil
+	}
+	v := NewVariable(name)
+	r[name] = v
+	return v, nil
+}
+
+// StrictResolver resolves names to the variables in the map and rejects
+// every other name with UnknownVariableErr.
+type StrictResolver map[string]*Variable
+
+func (r StrictResolver) ResolveVariable(name string) (*Variable, error) {
+	if v, exists := r[name]; exists {
+		return v, nil
+	}
+	return nil, errors.Wrapf(UnknownVariableErr, "'%s'", name)
+}
+
+// An attribute which is derived from an origin and a size the first time
+// it is used, like right == left + width.
+type derivedAttribute struct {
+	origin, size string
+	factor       float64
+}
+
+var derivedAttributes = map[string]derivedAttribute{
+	"right":   {"left", "width", 1},
+	"bottom":  {"top", "height", 1},
+	"centerX": {"left", "width", 0.5},
+	"centerY": {"top", "height", 0.5},
+}
+
+// NodeResolver resolves dotted node.attribute names, creating nodes and
+// attributes on first use. The first use of right, bottom, centerX or
+// centerY also adds the required constraint deriving it from left and
+// width or top and height to the solver.
+type NodeResolver struct {
+	// Nodes maps node names to their attributes.
+	Nodes  map[string]map[string]*Variable
+	solver *Solver
+}
+
+func NewNodeResolver(s *Solver) *NodeResolver {
+	return &NodeResolver{
+		Nodes:  map[string]map[string]*Variable{},
+		solver: s,
+	}
+}
+
+func (r *NodeResolver) ResolveVariable(name string) (*Variable, error) {
+	i := strings.LastIndexByte(name, '.')
+	if i <= 0 || i == len(name)-1 {
+		return nil, errors.Errorf("'%s' is not of the form node.attribute", name)
+	}
+	return r.Attribute(name[:i], name[i+1:])
+}
+
+// Attribute returns the variable for the attribute of the node.
+func (r *NodeResolver) Attribute(node, attribute string) (*Variable, error) {
+	attributes, exists := r.Nodes[node]
+	if !exists {
+		attributes = map[string]*Variable{}
+		r.Nodes[node] = attributes
+	}
+	if v, exists := attributes[attribute]; exists {
+		return v, nil
+	}
+
+	v := NewVariable(node + "." + attribute)
+	attributes[attribute] = v
+
+	if derived, exists := derivedAttributes[attribute]; exists {
+		origin, err := r.Attribute(node, derived.origin)
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.Attribute(node, derived.size)
+		if err != nil {
+			return nil, err
+		}
+		c := v.EqualsExpression(origin.AddTerm(size.Multiply(derived.factor)))
+		if err := r.solver.AddConstraint(c); err != nil {
+			delete(attributes, attribute)
+			return nil, errors.Wrapf(err, "can't derive %s.%s", node, attribute)
+		}
+	}
+	return v, nil
+}
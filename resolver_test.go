@@ -0,0 +1,91 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapResolver(t *testing.T) {
+	vr := MapResolver{}
+	c, err := NewConstraintParser().ParseConstraint("x == 2.5e1 + y", vr)
+	assert.NoError(t, err)
+	assert.Len(t, vr, 2)
+	assertSameConstraint(t, vr["x"].EqualsExpression(vr["y"].AddFloat(25)), c)
+
+	x, err := vr.ResolveVariable("x")
+	assert.NoError(t, err)
+	assert.True(t, vr["x"] == x)
+}
+
+func TestStrictResolver(t *testing.T) {
+	x := NewVariable("x")
+	vr := StrictResolver{"x": x}
+
+	v, err := vr.ResolveVariable("x")
+	assert.NoError(t, err)
+	assert.True(t, x == v)
+
+	_, err = vr.ResolveVariable("y")
+	assert.Equal(t, UnknownVariableErr, errors.Cause(err))
+	assert.Len(t, vr, 1)
+}
+
+type gutterResolver struct {
+	MapResolver
+}
+
+func (vr gutterResolver) ResolveConstant(name string) (*Expression, error) {
+	if name == "gutter" {
+		return NewExpression(15), nil
+	}
+	return nil, errors.New("not a constant")
+}
+
+func TestConstantResolver(t *testing.T) {
+	vr := gutterResolver{MapResolver{}}
+	c, err := NewConstraintParser().ParseConstraint("x == y + 2 * gutter", vr)
+	assert.NoError(t, err)
+	assert.NotContains(t, vr.MapResolver, "gutter")
+	assertSameConstraint(t, vr.MapResolver["x"].EqualsExpression(vr.MapResolver["y"].AddFloat(30)), c)
+}
+
+func TestNodeResolver(t *testing.T) {
+	solver := NewSolver()
+	vr := NewNodeResolver(solver)
+	cp := NewConstraintParser()
+
+	for _, raw := range []string{
+		"box.centerX == 50",
+		"box.width == 40",
+		"box.bottom == 100",
+		"box.centerY == 80",
+		"box.right <= 1000",
+	} {
+		c, err := cp.ParseConstraint(raw, vr)
+		assert.NoError(t, err, raw)
+		assert.NoError(t, solver.AddConstraint(c), raw)
+	}
+	solver.UpdateVariables()
+
+	box := vr.Nodes["box"]
+	for attribute, expected := range map[string]float64{
+		"left":    30,
+		"width":   40,
+		"right":   70,
+		"centerX": 50,
+		"top":     60,
+		"height":  40,
+		"bottom":  100,
+		"centerY": 80,
+	} {
+		assert.InDelta(t, expected, box[attribute].Value, Epsilon, attribute)
+	}
+	assert.Equal(t, "box.centerY", box["centerY"].Name)
+
+	for _, name := range []string{"box", ".left", "box."} {
+		_, err := vr.ResolveVariable(name)
+		assert.Error(t, err, name)
+	}
+}
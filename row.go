@@ -3,22 +3,25 @@ package cassgowary
 import (
 	"fmt"
 	"strings"
-
-	"github.com/emirpasic/gods/maps/linkedhashmap"
 )
 
 type row struct {
 	constant float64
-	cells    *linkedhashmap.Map
+	cells    *cellMap
+
+	// The basic symbol of the row and the column index of the tableau,
+	// both nil while the row is not part of a tableau.
+	basic   *symbol
+	columns *columnIndex
 }
 
 func (r *row) String() string {
 	info := []string{
 		fmt.Sprintf("constant:%f cells:", r.constant),
 	}
-	r.cells.Each(func(k, v interface{}) {
+	r.cells.Each(func(s *symbol, v float64) {
 		info = append(info,
-			fmt.Sprintf("%s:%f", k, v),
+			fmt.Sprintf("%s:%f", s, v),
 		)
 	})
 	return strings.Join(info, " ")
@@ -26,7 +29,7 @@ func (r *row) String() string {
 
 func newRow() *row {
 	return &row{
-		cells: linkedhashmap.New(),
+		cells: newCellMap(),
 	}
 }
 
@@ -38,11 +41,11 @@ func newRowWith(constant float64) *row {
 
 func newRowFrom(other *row) *row {
 	r := &row{
-		cells:    linkedhashmap.New(),
+		cells:    newCellMap(),
 		constant: other.constant,
 	}
-	other.cells.Each(func(k, v interface{}) {
-		r.cells.Put(k, v)
+	other.cells.Each(func(s *symbol, v float64) {
+		r.cells.Put(s, v)
 	})
 
 	return r
@@ -59,18 +62,16 @@ func (r *row) add(value float64) float64 {
 // added to the existing coefficient. If the resulting coefficient
 // is zero, the symbol will be removed from the row
 func (r *row) insertSymbol(s *symbol, coeffecient float64) {
-	coeffecientRaw, exists := r.cells.Get(s)
-	if exists {
-		existingCoefficient := coeffecientRaw.(float64)
+	if existingCoefficient, exists := r.cells.Get(s); exists {
 		coeffecient += existingCoefficient
 	}
 
 	if FloatNearZero(coeffecient) {
-		r.cells.Remove(s)
+		r.remove(s)
 		return
 	}
 
-	r.cells.Put(s, coeffecient)
+	r.put(s, coeffecient)
 }
 
 // Insert a symbol into the row with a given coefficient.
@@ -88,18 +89,15 @@ func (r *row) insertSymbolDefault(s *symbol) {
 func (r *row) insertRow(other *row, coefficient float64) {
 	r.constant += other.constant * coefficient
 
-	other.cells.Each(func(k, v interface{}) {
-		s := k.(*symbol)
-		coeff := v.(float64) * coefficient
+	other.cells.Each(func(s *symbol, v float64) {
+		coeff := v * coefficient
 
-		var temp float64
-		if x, exists := r.cells.Get(s); exists {
-			temp = x.(float64)
-		}
+		temp, _ := r.cells.Get(s)
 		temp += coeff
-		r.cells.Put(s, temp)
 		if FloatNearZero(temp) {
-			r.cells.Remove(s)
+			r.remove(s)
+		} else {
+			r.put(s, temp)
 		}
 	})
 }
@@ -109,14 +107,23 @@ func (r *row) insertFromDefault(other *row) {
 }
 
 func (r *row) remove(s *symbol) {
+	if r.columns != nil {
+		r.columns.remove(s, r)
+	}
 	r.cells.Remove(s)
 }
 
+// Set the coefficient of the symbol, keeping the column index in sync.
+func (r *row) put(s *symbol, coefficient float64) {
+	if r.columns != nil {
+		r.columns.add(s, r)
+	}
+	r.cells.Put(s, coefficient)
+}
+
 func (r *row) reverseSign() {
 	r.constant *= -1
-	r.cells.Each(func(k, v interface{}) {
-		r.cells.Put(k, -v.(float64))
-	})
+	r.cells.scale(-1)
 }
 
 // Solve the row for the given symbol.
@@ -127,19 +134,11 @@ func (r *row) reverseSign() {
 // be multiplied by the negative inverse of the target coefficient.
 // The given symbol *must* exist in the row.
 func (r *row) solveFor(s *symbol) {
-	v, _ := r.cells.Get(s)
-	value := v.(float64)
+	value, _ := r.cells.Get(s)
 	coeff := -1 / value
-	r.cells.Remove(s)
+	r.remove(s)
 	r.constant *= coeff
-
-	newCells := linkedhashmap.New()
-	r.cells.Each(func(k, v interface{}) {
-		value := v.(float64)
-		value *= coeff
-		newCells.Put(k, value)
-	})
-	r.cells = newCells
+	r.cells.scale(coeff)
 }
 
 //  Solve the row for the given symbols.
@@ -158,11 +157,8 @@ func (r *row) solveForSymbols(lhs, rhs *symbol) {
 // <p/>
 // If the symbol does not exist in the row, zero will be returned.
 func (r *row) coefficientFor(s *symbol) float64 {
-	if v, exists := r.cells.Get(s); exists {
-		f := v.(float64)
-		return f
-	}
-	return 0
+	v, _ := r.cells.Get(s)
+	return v
 }
 
 // Substitute a symbol with the data from another row.
@@ -172,17 +168,15 @@ func (r *row) coefficientFor(s *symbol) float64 {
 // expression 3 * a * y + a * c + b.
 // If the symbol does not exist in the row, this is a no-op.
 func (r *row) substitute(s *symbol, other *row) {
-	if c, exists := r.cells.Get(s); exists {
-		coefficient := c.(float64)
-		r.cells.Remove(s)
+	if coefficient, exists := r.cells.Get(s); exists {
+		r.remove(s)
 		r.insertRow(other, coefficient)
 	}
 }
 
 // Test whether a row is composed of all dummy variables.
 func (r *row) allDummies() bool {
-	return r.cells.All(func(k, v interface{}) bool {
-		s := k.(*symbol)
-		return s != nil && s.kind == symbolDummy
+	return r.cells.All(func(s *symbol, _ float64) bool {
+		return s.kind == symbolDummy
 	})
 }
@@ -0,0 +1,112 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Layouts whose coefficients span many orders of magnitude, as they do
+// when lengths in different units or rounding residues end up in the
+// same constraint.
+var badlyScaledLayouts = []struct {
+	name   string
+	layout func(t *testing.T, solver *Solver)
+}{
+	{
+		name: "tiny units",
+		layout: func(t *testing.T, solver *Solver) {
+			x := NewVariable("x")
+			assert.NoError(t, solver.AddConstraint(NewExpression(-1e-11, x.Multiply(1e-13)).EqualsFloat(0)))
+			solver.UpdateVariables()
+			assert.InDelta(t, 100, x.Value, 1e-9)
+		},
+	},
+	{
+		name: "mixed units through substitution",
+		layout: func(t *testing.T, solver *Solver) {
+			km := NewVariable("km")
+			mm := NewVariable("mm")
+			label := NewVariable("label")
+			assert.NoError(t, solver.AddConstraint(NewExpression(-3, NewTermFrom(mm), km.Multiply(-1e-6)).EqualsFloat(0)))
+			assert.NoError(t, solver.AddConstraint(NewExpression(0, label.Multiply(1e-7), mm.Multiply(-1e-7)).EqualsFloat(0)))
+			assert.NoError(t, solver.AddConstraint(km.EqualsFloat(1e6)))
+			solver.UpdateVariables()
+			assert.InDelta(t, 4, mm.Value, 1e-9)
+			assert.InDelta(t, 4, label.Value, 1e-9)
+		},
+	},
+	{
+		name: "rounding residue in the primal ratio test",
+		layout: func(t *testing.T, solver *Solver) {
+			x := NewVariable("x")
+			y := NewVariable("y")
+			assert.NoError(t, solver.AddConstraint(x.Add(y).GreaterThanOrEqualToFloat(95)))
+			assert.NoError(t, solver.AddConstraint(NewExpression(-425, x.Multiply(5), y.Multiply(-1e-11)).EqualsFloat(0)))
+			assert.NoError(t, solver.AddEditVariable(x, Weak))
+			assert.NoError(t, solver.SuggestValue(x, 172))
+			solver.UpdateVariables()
+			assert.InDelta(t, 85, x.Value, 1e-6)
+			assert.InDelta(t, 10, y.Value, 1e-6)
+		},
+	},
+	{
+		name: "rounding residue in the dual ratio test",
+		layout: func(t *testing.T, solver *Solver) {
+			x := NewVariable("x")
+			y := NewVariable("y")
+			assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(50)))
+			assert.NoError(t, solver.AddConstraint(NewExpression(-255, y.Multiply(5), x.Multiply(-1e-9)).EqualsFloat(0)))
+			assert.NoError(t, solver.AddEditVariable(y, Weak))
+			assert.NoError(t, solver.SuggestValue(y, 51))
+			assert.NoError(t, solver.SuggestValue(y, 142))
+			solver.UpdateVariables()
+			assert.InDelta(t, 50, x.Value, 1e-6)
+			assert.InDelta(t, 51, y.Value, 1e-6)
+		},
+	},
+	{
+		name: "tiny units in a soft constraint",
+		layout: func(t *testing.T, solver *Solver) {
+			x := NewVariable("x")
+			assert.NoError(t, solver.AddConstraint(NewExpression(-1e-11, x.Multiply(1e-13)).EqualsFloat(0).NewModifyStrength(Strong)))
+			solver.UpdateVariables()
+			assert.InDelta(t, 100, x.Value, 1e-9)
+		},
+	},
+}
+
+func TestBadlyScaledLayouts(t *testing.T) {
+	for _, l := range badlyScaledLayouts {
+		t.Run(l.name, func(t *testing.T) {
+			l.layout(t, NewSolver())
+		})
+	}
+}
+
+func TestRowScaleIsPowerOfTwo(t *testing.T) {
+	x := NewVariable("x")
+	y := NewVariable("y")
+	assert.Equal(t, float64(1), rowScale(NewExpression(3, NewTermFrom(x), y.Multiply(0.5))))
+	assert.Equal(t, 0.25, rowScale(NewExpression(0, x.Multiply(6))))
+	assert.Equal(t, float64(1024), rowScale(NewExpression(0, x.Multiply(1e-3))))
+	assert.Equal(t, float64(1), rowScale(NewExpression(5)))
+}
+
+// Scaling a soft row scales its error as well, which must not change how
+// much the constraint weighs against the others.
+func TestScaledSoftRowKeepsWeight(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+	// The error of 3x == 30 weighs 3 per unit of x and the error of
+	// 1.9x == 0 weighs 1.9, though the first row is scaled by 0.5.
+	assert.NoError(t, solver.AddConstraint(x.Multiply(3).EqualsFloat(30).NewModifyStrength(Medium)))
+	assert.NoError(t, solver.AddConstraint(x.Multiply(1.9).EqualsFloat(0).NewModifyStrength(Medium)))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+
+	assert.NoError(t, solver.AddEditVariable(x, Weak))
+	assert.NoError(t, solver.SuggestValue(x, 4))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+}
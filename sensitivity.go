@@ -0,0 +1,75 @@
+package cassgowary
+
+import (
+	"math"
+)
+
+// EditRange returns the range the edit variable can be suggested in
+// without changing the optimal basis of the current tableau. Within it
+// every variable moves linearly with the suggested value, at the rate
+// given by Sensitivity. The limits may be infinite.
+func (s *Solver) EditRange(v *Variable) (lower, upper float64, err error) {
+	e, exists := s.edits.Get(v)
+	if !exists {
+		return 0, 0, UnknownEditVariableErr
+	}
+	edit := e.(*editInfo)
+	cp := edit.tag.owner
+
+	// A suggestion moves the row constants the same way suggestValue
+	// does. The basis stays optimal as long as every restricted basic
+	// symbol stays feasible.
+	lower, upper = math.Inf(-1), math.Inf(1)
+	if r, exists := cp.rows.Get(edit.tag.marker); exists {
+		return lower, edit.constant + r.constant, nil
+	}
+	if r, exists := cp.rows.Get(edit.tag.other); exists {
+		return edit.constant - r.constant, upper, nil
+	}
+
+	for _, r := range cp.columns.rowsWith(edit.tag.marker) {
+		coefficient := r.coefficientFor(edit.tag.marker)
+		if coefficient == 0 || r.basic.kind == symbolExternal {
+			continue
+		}
+		limit := edit.constant - r.constant/coefficient
+		if coefficient > 0 {
+			lower = math.Max(lower, limit)
+		} else {
+			upper = math.Min(upper, limit)
+		}
+	}
+	return lower, upper, nil
+}
+
+// Sensitivity returns d(target)/d(edit), the rate at which the target
+// moves with the value suggested for the edit variable. It holds within
+// the range returned by EditRange.
+func (s *Solver) Sensitivity(target, edit *Variable) (float64, error) {
+	e, exists := s.edits.Get(edit)
+	if !exists {
+		return 0, UnknownEditVariableErr
+	}
+	tag := e.(*editInfo).tag
+
+	target, _ = s.resolveVariable(target)
+	if target == nil {
+		return 0, nil
+	}
+	sym, exists := s.vars.Get(target)
+	if !exists {
+		return 0, nil
+	}
+
+	cp := tag.owner
+	if _, exists := cp.rows.Get(tag.marker); exists {
+		return 0, nil
+	}
+	if _, exists := cp.rows.Get(tag.other); exists {
+		return 0, nil
+	}
+	if r, exists := cp.rows.Get(sym.(*symbol)); exists {
+		return r.coefficientFor(tag.marker), nil
+	}
+	return 0, nil
+}
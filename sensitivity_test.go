@@ -0,0 +1,113 @@
+package cassgowary
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type resizableWindow struct {
+	solver                     *Solver
+	left, width, right, center *Variable
+}
+
+func newResizableWindow(t *testing.T, options ...SolverOption) *resizableWindow {
+	w := &resizableWindow{
+		solver: NewSolver(options...),
+		left:   NewVariable("left"),
+		width:  NewVariable("width"),
+		right:  NewVariable("right"),
+		center: NewVariable("center"),
+	}
+	for _, c := range []*Constraint{
+		w.left.EqualsFloat(10),
+		w.right.EqualsExpression(w.left.Add(w.width)),
+		w.center.EqualsExpression(w.left.AddTerm(w.width.Divide(2))),
+		w.width.GreaterThanOrEqualToFloat(100),
+		w.width.LessThanOrEqualToFloat(300),
+	} {
+		assert.NoError(t, w.solver.AddConstraint(c))
+	}
+	assert.NoError(t, w.solver.AddEditVariable(w.width, Strong))
+	return w
+}
+
+func TestEditRangeWithinBounds(t *testing.T) {
+	for _, presolve := range []bool{false, true} {
+		var options []SolverOption
+		if presolve {
+			options = append(options, WithPresolve())
+		}
+		w := newResizableWindow(t, options...)
+		assert.NoError(t, w.solver.SuggestValue(w.width, 200))
+
+		lower, upper, err := w.solver.EditRange(w.width)
+		assert.NoError(t, err)
+		assert.InDelta(t, 100, lower, Epsilon)
+		assert.InDelta(t, 300, upper, Epsilon)
+
+		for v, expected := range map[*Variable]float64{
+			w.width:  1,
+			w.right:  1,
+			w.center: 0.5,
+			w.left:   0,
+		} {
+			rate, err := w.solver.Sensitivity(v, w.width)
+			assert.NoError(t, err)
+			assert.InDelta(t, expected, rate, Epsilon, v.Name)
+		}
+	}
+}
+
+func TestEditRangeAtBound(t *testing.T) {
+	w := newResizableWindow(t)
+	assert.NoError(t, w.solver.SuggestValue(w.width, 400))
+
+	lower, upper, err := w.solver.EditRange(w.width)
+	assert.NoError(t, err)
+	assert.InDelta(t, 300, lower, Epsilon)
+	assert.True(t, math.IsInf(upper, 1))
+
+	rate, err := w.solver.Sensitivity(w.right, w.width)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), rate)
+}
+
+// Within the range the values follow from the sensitivities, without
+// asking the solver.
+func TestSensitivityPredictsValues(t *testing.T) {
+	w := newResizableWindow(t)
+	assert.NoError(t, w.solver.SuggestValue(w.width, 150))
+	w.solver.UpdateVariables()
+
+	lower, upper, err := w.solver.EditRange(w.width)
+	assert.NoError(t, err)
+
+	vars := []*Variable{w.left, w.width, w.right, w.center}
+	start := make([]float64, len(vars))
+	rates := make([]float64, len(vars))
+	for i, v := range vars {
+		start[i] = v.Value
+		rates[i], err = w.solver.Sensitivity(v, w.width)
+		assert.NoError(t, err)
+	}
+
+	for value := lower; value <= upper; value += (upper - lower) / 7 {
+		assert.NoError(t, w.solver.SuggestValue(w.width, value))
+		w.solver.UpdateVariables()
+		for i, v := range vars {
+			assert.InDelta(t, start[i]+rates[i]*(value-150), v.Value, 1e-9, v.Name)
+		}
+	}
+}
+
+func TestEditRangeUnknownEdit(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	_, _, err := solver.EditRange(x)
+	assert.Equal(t, UnknownEditVariableErr, err)
+	_, err = solver.Sensitivity(x, x)
+	assert.Equal(t, UnknownEditVariableErr, err)
+}
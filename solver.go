@@ -4,12 +4,36 @@ import (
 	"math"
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/emirpasic/gods/sets/linkedhashset"
 
 	"github.com/pkg/errors"
 )
 
 type tag struct {
 	marker, other *symbol
+	owner         *component
+	// The error symbols of the bounds of a soft range, whose marker and
+	// other are the slacks of its lower and upper bound.
+	lowerError, upperError *symbol
+	// The factor the row was scaled by.
+	scale float64
+}
+
+// Get the objective coefficient of the error symbols of the constraint.
+// The errors of a scaled row are scaled as well, which the weight undoes.
+func (t *tag) weight(c *Constraint) float64 {
+	return float64(c.Strength) / t.scale
+}
+
+// Get the symbols of the tag which are set.
+func (t *tag) symbols() []*symbol {
+	symbols := make([]*symbol, 0, 4)
+	for _, sym := range []*symbol{t.marker, t.other, t.lowerError, t.upperError} {
+		if sym != nil {
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols
 }
 
 type editInfo struct {
@@ -27,24 +51,54 @@ func newEditInfo(c *Constraint, t *tag, constant float64) *editInfo {
 }
 
 type Solver struct {
-	cns                   *linkedhashmap.Map //[*Constraint]tag
-	rows                  *linkedhashmap.Map //[Symbol]*row
-	vars                  *linkedhashmap.Map //[*Variable]Symbol
-	edits                 *linkedhashmap.Map //[*Variable]editInfo
-	infeasibleRows        symbols
-	objective, artificial *row
+	cns        *linkedhashmap.Map //[*Constraint]*tag
+	vars       *linkedhashmap.Map //[*Variable]*symbol
+	edits      *linkedhashmap.Map //[*Variable]*editInfo
+	owners     *linkedhashmap.Map //[*Variable]*component
+	components *linkedhashset.Set //*component
+	stays      *linkedhashmap.Map //[*Variable]editInfo
+	broken     *linkedhashmap.Map //[*Constraint]*Constraint
+	groups     *linkedhashmap.Map //[string]*ConstraintGroup
+	grouped    map[*Constraint]int
+	refs       map[*Variable]int // constraints mentioning the variable
+	aliases    map[*Variable]*alias
+	aliasCns   *linkedhashmap.Map //[*Constraint]*alias
+	// The constraints defining the variables of Min, Max and Abs, by the
+	// constraint using them.
+	definitions map[*Constraint][]*Constraint
+
+	implicitStays    bool
+	presolve         bool
+	recovery         bool
+	recoveryStrength Strength
+	warn             func(w Warning)
+	tracer           Tracer
+	pivots           int
+	refreshPivots    int
+	refreshTolerance float64
 }
 
-func NewSolver() *Solver {
-	return &Solver{
-		cns:            linkedhashmap.New(),
-		rows:           linkedhashmap.New(),
-		vars:           linkedhashmap.New(),
-		edits:          linkedhashmap.New(),
-		infeasibleRows: symbols{},
-		objective:      newRow(),
-		artificial:     nil,
-	}
+func NewSolver(options ...SolverOption) *Solver {
+	s := &Solver{
+		cns:        linkedhashmap.New(),
+		vars:       linkedhashmap.New(),
+		edits:      linkedhashmap.New(),
+		owners:     linkedhashmap.New(),
+		components: linkedhashset.New(),
+		stays:      linkedhashmap.New(),
+		broken:     linkedhashmap.New(),
+		groups:     linkedhashmap.New(),
+		grouped:    map[*Constraint]int{},
+		refs:       map[*Variable]int{},
+		aliases:    map[*Variable]*alias{},
+		aliasCns:   linkedhashmap.New(),
+
+		definitions: map[*Constraint][]*Constraint{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
 func (s *Solver) AddVariable(name string) {
@@ -53,139 +107,508 @@ func (s *Solver) AddVariable(name string) {
 
 // AddConstraint adds a constraint to the solver.
 func (s *Solver) AddConstraint(c *Constraint) error {
+	return s.add(c, false)
+}
+
+// Add the constraint. With restore, a constraint which can't be added
+// leaves the tableau as it was, rather than with the rows of the failed
+// attempt, which AddConstraint keeps as it always did.
+func (s *Solver) add(c *Constraint, restore bool) error {
+	if _, exists := s.broken.Get(c); exists {
+		return DuplicateConstraintErr(c)
+	}
 	if _, exists := s.cns.Get(c); exists {
 		return DuplicateConstraintErr(c)
 	}
 
-	t := &tag{}
+	definitions, _, err := lowerPiecewise(c)
+	if err != nil {
+		return err
+	}
+	for i, d := range definitions {
+		if err := s.addConstraint(d, restore); err != nil {
+			s.removeDefinitions(definitions[:i])
+			return errors.Wrap(err, "can't add definition")
+		}
+	}
 
-	r, err := s.createRow(c, t)
+	// Recovery retries on the tableau from before the attempt.
+	err = s.addConstraint(c, restore || s.recovery && c.Strength == Required)
+	if err != nil && s.recovery && c.Strength == Required && IsUnsatisfiable(err) {
+		err = s.breakConstraint(c, err)
+	}
+	if err != nil {
+		s.removeDefinitions(definitions)
+		return err
+	}
+	if len(definitions) > 0 {
+		s.definitions[c] = definitions
+	}
+
+	if s.implicitStays {
+		if err := s.addStays(c); err != nil {
+			return err
+		}
+	}
+	return s.autoRefresh()
+}
+
+// Add the stays of the variables the constraint mentions. If one can't be
+// added, the stays added so far and the constraint are removed again.
+func (s *Solver) addStays(c *Constraint) error {
+	var added []*Variable
+	for _, v := range s.mentionedVariables(c) {
+		if _, exists := s.stays.Get(v); exists {
+			continue
+		}
+		if err := s.addStay(v); err != nil {
+			for _, v := range added {
+				if rerr := s.removeStay(v); rerr != nil {
+					return rerr
+				}
+			}
+			if rerr := s.RemoveConstraint(c); rerr != nil {
+				return rerr
+			}
+			return errors.Wrap(err, "can't add stay")
+		}
+		added = append(added, v)
+	}
+	return nil
+}
+
+// Add the constraint to the tableau, restoring the tableau of its
+// component if it can't be added and restore is set.
+func (s *Solver) addConstraint(c *Constraint, restore bool) error {
+	if _, exists := s.cns.Get(c); exists {
+		return DuplicateConstraintErr(c)
+	}
+	if _, exists := s.aliasCns.Get(c); exists {
+		return DuplicateConstraintErr(c)
+	}
+
+	if s.presolve {
+		if a := s.aliasFor(c); a != nil {
+			s.addAlias(a)
+			return nil
+		}
+	}
+
+	cp, merged := s.componentFor(c)
+	t := &tag{owner: cp}
+	if err := s.addRow(cp, c, t); err != nil {
+		s.releaseVariables(cp, s.resolved(c).variables())
+		if merged {
+			s.splitComponent(cp)
+		} else if cp.cns.Empty() {
+			s.components.Remove(cp)
+		}
+		if restore && s.components.Contains(cp) {
+			if rerr := s.rebuild(cp); rerr != nil {
+				return errors.Wrapf(rerr, "can't restore the tableau after: %v", err)
+			}
+		}
+		return err
+	}
+
+	s.cns.Put(c, t)
+	cp.cns.Put(c, t)
+	for _, v := range s.resolved(c).variables() {
+		cp.vars.Add(v)
+		s.owners.Put(v, cp)
+		s.refs[v]++
+	}
+
+	if s.tracer != nil {
+		s.tracer.ConstraintAdded(c)
+	}
+	return nil
+}
+
+// Add the row for the constraint to the component and optimize it.
+func (s *Solver) addRow(cp *component, c *Constraint, t *tag) error {
+	r, err := s.createRow(cp, c, t)
 	if err != nil {
 		return errors.Wrap(err, "can't create row")
 	}
-	subject := s.chooseSubject(r, t)
+	if err := s.enterRow(cp, c, r, t); err != nil {
+		return err
+	}
+	if c.Op == OP_RANGE {
+		return s.addUpperBound(cp, c, t)
+	}
+	return nil
+}
+
+// Enter the row into the tableau, solved for a subject chosen with the
+// tag, and optimize the component.
+func (s *Solver) enterRow(cp *component, c *Constraint, r *row, t *tag) error {
+	if s.tracer != nil {
+		s.tracer.RowCreated(c, r.String())
+	}
+	subject := cp.chooseSubject(r, t)
 
-	switch {
-	case subject.kind == symbolInvalid && r.allDummies():
-		if FloatNearZero(r.constant) {
+	if subject.kind == symbolInvalid && r.allDummies() {
+		if !FloatNearZero(r.constant) {
 			return UnsatisfiableConstraintErr(c)
-		} else {
-			subject = t.marker
 		}
+		subject = t.marker
+	}
 
-	case subject.kind == symbolInvalid:
-		if added, err := s.addWithArtificialVariable(r); !added || err != nil {
+	if s.tracer != nil {
+		s.tracer.SubjectChosen(c, subject.String())
+	}
+
+	if subject.kind == symbolInvalid {
+		if s.tracer != nil {
+			s.tracer.ArtificialStart(c)
+		}
+		added, err := cp.addWithArtificialVariable(r)
+		if s.tracer != nil {
+			s.tracer.ArtificialEnd(c, added && err == nil)
+		}
+		if !added || err != nil {
 			return UnsatisfiableConstraintErr(c)
 		}
-
-	default:
+	} else {
 		r.solveFor(subject)
-		s.substitute(subject, r)
-		s.rows.Put(subject, r)
+		cp.substitute(subject, r)
+		cp.putRow(subject, r)
 	}
 
-	s.cns.Put(c, t)
-	s.optimize(s.objective)
+	return cp.optimize(cp.objective)
+}
+
+// Add the second row of a range, whose first row was entered like the
+// row of an inequality expression >= 0. The lower bound of the row is
+//
+//	expression = lower slack - lower error
+//
+// so the upper bound expression + upper slack - upper error = span only
+// needs the symbols of the lower bound:
+//
+//	lower slack - lower error + upper slack - upper error - span = 0
+//
+// The tag ends up with the slack of either bound as marker and other.
+func (s *Solver) addUpperBound(cp *component, c *Constraint, t *tag) error {
+	upper := &tag{owner: cp, marker: newSymbolFrom(symbolSlack), scale: t.scale}
+	if c.Strength < Required {
+		upper.other = newSymbolFrom(symbolError)
+	}
+
+	r := newRowWith(-c.span * t.scale)
+	for _, sym := range []*symbol{t.marker, t.other, upper.marker, upper.other} {
+		if sym == nil {
+			continue
+		}
+		coefficient := float64(1)
+		if sym.kind == symbolError {
+			coefficient = -1
+		}
+		if basicRow, exists := cp.rows.Get(sym); exists {
+			r.insertRow(basicRow, coefficient)
+		} else {
+			r.insertSymbol(sym, coefficient)
+		}
+	}
+	if upper.other != nil {
+		cp.objective.insertSymbol(upper.other, t.weight(c))
+	}
+	if r.constant < 0.0 {
+		r.reverseSign()
+	}
 
+	if err := s.enterRow(cp, c, r, upper); err != nil {
+		if upper.other != nil {
+			cp.removeMarkerEffects(upper.other, t.weight(c))
+		}
+		if rerr := cp.removeRow(c, t); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+
+	t.lowerError, t.upperError = t.other, upper.other
+	t.other = upper.marker
 	return nil
 }
 
 func (s *Solver) RemoveConstraint(c *Constraint) error {
-	t, exists := s.cns.Get(c)
+	if err := s.remove(c); err != nil {
+		return err
+	}
+	// The active groups holding the constraint no longer keep it.
+	delete(s.grouped, c)
+	return nil
+}
+
+// Remove the constraint with the variables defined for it, leaving the
+// group counts to the caller.
+func (s *Solver) remove(c *Constraint) error {
+	if definitions, exists := s.definitions[c]; exists {
+		if err := s.dropConstraint(c); err != nil {
+			return err
+		}
+		delete(s.definitions, c)
+		return s.removeDefinitions(definitions)
+	}
+	return s.dropConstraint(c)
+}
+
+// Remove the constraints defining the variables of Min, Max and Abs.
+func (s *Solver) removeDefinitions(definitions []*Constraint) error {
+	for _, d := range definitions {
+		if err := s.dropConstraint(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove the constraint, releasing the variables no other constraint
+// mentions.
+func (s *Solver) dropConstraint(c *Constraint) error {
+	if replacement, exists := s.broken.Get(c); exists {
+		s.broken.Remove(c)
+		c = replacement.(*Constraint)
+	}
+	if a, exists := s.aliasCns.Get(c); exists {
+		return s.removeAlias(a.(*alias))
+	}
+
+	x, exists := s.cns.Get(c)
 	if !exists {
 		return UnknownConstraintErr(c)
 	}
 
-	tag := t.(*tag)
-	s.cns.Remove(c)
-	s.removeConstraintEffects(c, tag)
+	cp := x.(*tag).owner
+	if err := s.removeConstraint(c); err != nil {
+		return err
+	}
 
-	if r, exists := s.rows.Get(tag.marker); exists {
-		r.(*row).cells.Remove(tag.marker)
-	} else {
-		r := s.markerLeavingRow(tag.marker)
-		if r == nil {
-			return InternalSolverErr
+	// A stay alone doesn't keep a variable alive.
+	vars := s.resolved(c).variables()
+	for _, v := range vars {
+		s.refs[v]--
+		if x, exists := s.stays.Get(v); exists && s.refs[v] == 1 {
+			s.stays.Remove(v)
+			if err := s.removeConstraint(x.(*editInfo).constraint); err != nil {
+				return err
+			}
+			s.refs[v]--
 		}
+	}
+	s.releaseVariables(cp, vars)
 
-		//This looks wrong! changes made below
-		//Symbol leaving = tag.marker;
-		//rows.remove(tag.marker);
+	if err := cp.optimize(cp.objective); err != nil {
+		return err
+	}
+	if err := s.splitComponent(cp); err != nil {
+		return err
+	}
+	return s.autoRefresh()
+}
 
-		leaving := newSymbol()
-		if fs, _ := s.rows.Find(func(k, v interface{}) bool {
-			return r == v
-		}); fs != nil {
-			leaving = fs.(*symbol)
-		}
+// Remove the row of a registered constraint from its component.
+func (s *Solver) removeConstraint(c *Constraint) error {
+	x, _ := s.cns.Get(c)
+	t := x.(*tag)
+	s.cns.Remove(c)
+	t.owner.cns.Remove(c)
 
-		if leaving != nil && leaving.kind == symbolInvalid {
-			return InternalSolverErr
+	if err := t.owner.removeRow(c, t); err != nil {
+		return err
+	}
+	if s.tracer != nil {
+		s.tracer.ConstraintRemoved(c)
+	}
+	return nil
+}
+
+// Forget the variables which no constraint mentions any more.
+// Their symbols are dropped from the tableau of the component, so
+// UpdateVariables no longer writes to them.
+func (s *Solver) releaseVariables(cp *component, vars []*Variable) {
+	for _, v := range vars {
+		if s.refs[v] > 0 {
+			continue
 		}
+		delete(s.refs, v)
 
-		s.rows.Remove(leaving)
-		r.solveForSymbols(leaving, tag.marker)
-		s.substitute(tag.marker, r)
+		x, exists := s.vars.Get(v)
+		if !exists {
+			continue
+		}
+		s.vars.Remove(v)
+		cp.dropSymbol(x.(*symbol))
+		cp.vars.Remove(v)
+		s.owners.Remove(v)
 	}
-	s.optimize(s.objective)
-	return nil
 }
 
-func (s *Solver) removeConstraintEffects(c *Constraint, t *tag) {
-	if t.marker != nil && t.marker.kind == symbolError {
-		s.removeMarkerEffects(t.marker, float64(c.Strength))
-	} else if t.other != nil && t.other.kind == symbolError {
-		s.removeMarkerEffects(t.other, float64(c.Strength))
+// componentFor returns the component the constraint belongs to.
+// The components of all the variables it mentions are merged into one,
+// if it mentions no known variable a new component is started.
+func (s *Solver) componentFor(c *Constraint) (cp *component, merged bool) {
+	for _, v := range s.resolved(c).variables() {
+		x, exists := s.owners.Get(v)
+		if !exists {
+			continue
+		}
+		owner := x.(*component)
+		switch {
+		case cp == nil:
+			cp = owner
+		case cp != owner:
+			cp = s.mergeComponents(cp, owner)
+			merged = true
+		}
+	}
+
+	if cp == nil {
+		cp = newComponent(s)
+		s.components.Add(cp)
 	}
+	return cp, merged
 }
 
-func (s *Solver) removeMarkerEffects(marker *symbol, strength float64) {
-	if r, exists := s.rows.Get(marker); exists {
-		s.objective.insertRow(r.(*row), -strength)
-	} else {
-		s.objective.insertSymbol(marker, -strength)
+// Merge the smaller of the two components into the larger one.
+// The symbols of both are disjoint, so the rows can be moved over
+// as they are and the objectives simply added.
+func (s *Solver) mergeComponents(a, b *component) *component {
+	if a.cns.Size() < b.cns.Size() {
+		a, b = b, a
 	}
+
+	b.rows.Each(func(sym *symbol, r *row) {
+		a.putRow(sym, r)
+	})
+	a.objective.insertRow(b.objective, 1)
+	b.cns.Each(func(k, v interface{}) {
+		v.(*tag).owner = a
+		a.cns.Put(k, v)
+	})
+	b.vars.Each(func(_ int, v interface{}) {
+		a.vars.Add(v)
+		s.owners.Put(v, a)
+	})
+
+	s.components.Remove(b)
+	return a
 }
 
-func (s *Solver) markerLeavingRow(marker *symbol) *row {
-	r1, r2 := math.MaxFloat64, math.MaxFloat64
-	var first, second, third *row
+// Split the component after a constraint left it.
+// Variables no longer referenced by any of its constraints are released.
+// If the remaining constraints fall apart into several independent groups,
+// every group is rebuilt as a component of its own.
+func (s *Solver) splitComponent(cp *component) error {
+	groups := newUnionFind()
+	cp.cns.Each(func(k, _ interface{}) {
+		vars := s.resolved(k.(*Constraint)).variables()
+		for _, v := range vars {
+			groups.union(vars[0], v)
+		}
+	})
 
-	s.rows.Each(func(k, v interface{}) {
-		symbol := k.(*symbol)
-		candidate := v.(*row)
+	// Removing from the set while iterating it would skip elements.
+	var unused []interface{}
+	cp.vars.Each(func(_ int, v interface{}) {
+		if !groups.contains(v) {
+			unused = append(unused, v)
+		}
+	})
+	for _, v := range unused {
+		cp.vars.Remove(v)
+		s.owners.Remove(v)
+	}
 
-		c := candidate.coefficientFor(marker)
-		if c == 0 {
-			return
+	if cp.cns.Empty() {
+		s.components.Remove(cp)
+		return nil
+	}
+
+	roots := linkedhashmap.New() //[root]*component
+	cp.cns.Each(func(k, v interface{}) {
+		var root interface{} = cp
+		if vars := s.resolved(k.(*Constraint)).variables(); len(vars) > 0 {
+			root = groups.find(vars[0])
 		}
+		x, exists := roots.Get(root)
+		if !exists {
+			x = newComponent(s)
+			roots.Put(root, x)
+		}
+		part := x.(*component)
+		part.cns.Put(k, v)
+	})
 
-		if symbol.kind == symbolExternal {
-			third = candidate
-		} else if c < 0 {
-			r := -candidate.constant / c
-			if r < r1 {
-				r1 = r
-				first = candidate
-			}
-		} else {
-			if r := candidate.constant / c; r < r2 {
-				r2 = r
-				second = candidate
+	if roots.Size() == 1 {
+		return nil
+	}
+
+	s.components.Remove(cp)
+	var err error
+	roots.Each(func(_, x interface{}) {
+		part := x.(*component)
+		s.components.Add(part)
+		part.cns.Each(func(k, _ interface{}) {
+			for _, v := range s.resolved(k.(*Constraint)).variables() {
+				part.vars.Add(v)
+				s.owners.Put(v, part)
 			}
+		})
+		if rerr := s.rebuild(part); rerr != nil && err == nil {
+			err = rerr
 		}
 	})
+	return err
+}
 
-	if first != nil {
-		return first
+// Rebuild the tableau of a component from its registered constraints.
+// The tags are reused so edit information stays attached to them, and
+// the current edit constants are suggested again afterwards.
+func (s *Solver) rebuild(cp *component) error {
+	cp.rows.Clear()
+	cp.columns = newColumnIndex()
+	cp.objective = newRow()
+	cp.artificial = nil
+	cp.infeasibleRows = cp.infeasibleRows[:0]
+
+	var err error
+	cp.cns.Each(func(k, v interface{}) {
+		c, t := k.(*Constraint), v.(*tag)
+		*t = tag{owner: cp}
+		if aerr := s.addRow(cp, c, t); aerr != nil && err == nil {
+			err = errors.Wrap(aerr, "can't rebuild constraint")
+		}
+	})
+	if err != nil {
+		return err
 	}
-	if second != nil {
-		return second
+
+	resuggest := func(_, v interface{}) {
+		edit := v.(*editInfo)
+		if edit.tag.owner != cp || edit.constant == 0 {
+			return
+		}
+		value := edit.constant
+		edit.constant = 0
+		if serr := cp.suggestValue(edit, value); serr != nil && err == nil {
+			err = serr
+		}
 	}
-	return third
+	s.edits.Each(resuggest)
+	s.stays.Each(resuggest)
+	return err
 }
 
-func (s *Solver) HasConstraint(c Constraint) bool {
+func (s *Solver) HasConstraint(c *Constraint) bool {
+	if _, exists := s.broken.Get(c); exists {
+		return true
+	}
+	if _, exists := s.aliasCns.Get(c); exists {
+		return true
+	}
 	_, exists := s.cns.Get(c)
 	return exists
 }
@@ -222,10 +645,10 @@ func (s *Solver) AddEditVariable(v *Variable, strength Strength) error {
 
 func (s *Solver) RemoveEditVariable(v *Variable) error {
 	e, exists := s.edits.Get(v)
-	edit := e.(*editInfo)
 	if !exists {
 		return UnknownEditVariableErr
 	}
+	edit := e.(*editInfo)
 
 	if err := s.RemoveConstraint(edit.constraint); err != nil {
 		return UnknownConstraintErr(edit.constraint)
@@ -240,70 +663,82 @@ func (s *Solver) HasEditVariable(v *Variable) bool {
 	return exists
 }
 
-func (s *Solver) SuggestValue(v Variable, value float64) error {
+func (s *Solver) SuggestValue(v *Variable, value float64) error {
 	e, exists := s.edits.Get(v)
-	edit := e.(*editInfo)
 	if !exists {
 		return UnknownEditVariableErr
 	}
+	edit := e.(*editInfo)
 
-	delta := value - edit.constant
-	edit.constant = value
-
-	x, exists := s.rows.Get(edit.tag.marker)
-	r := x.(*row)
-	if exists {
-		if r.add(-delta) < 0.0 {
-			s.infeasibleRows = append(
-				s.infeasibleRows,
-				edit.tag.marker,
-			)
-		}
-		return s.dualOptimize()
+	if s.tracer != nil {
+		s.tracer.EditSuggested(v, value)
 	}
-
-	x, exists = s.rows.Get(edit.tag.other)
-	r = x.(*row)
-	if exists {
-		if r.add(delta) < 0 {
-			s.infeasibleRows = append(
-				s.infeasibleRows,
-				edit.tag.other,
-			)
-		}
-		return s.dualOptimize()
+	if err := edit.tag.owner.suggestValue(edit, value); err != nil {
+		return err
 	}
-
-	s.rows.Each(func(k, v interface{}) {
-		symbol := k.(*symbol)
-		r := v.(*row)
-		coefficient := r.coefficientFor(edit.tag.marker)
-		if coefficient != 0.0 &&
-			r.add(delta*coefficient) < 0.0 &&
-			symbol != nil &&
-			symbol.kind == symbolExternal {
-			s.infeasibleRows = append(
-				s.infeasibleRows,
-				symbol,
-			)
-		}
-	})
-
-	return s.dualOptimize()
+	return s.autoRefresh()
 }
 
-func (s *Solver) UpdateVariables() {
+// UpdateVariables writes the solution to the variables. With implicit
+// stays, the stays are moved to the new values, which fails only if the
+// tableau can't be made feasible again.
+func (s *Solver) UpdateVariables() error {
 	s.vars.Each(func(k, v interface{}) {
 		variable := k.(*Variable)
 		symbol := v.(*symbol)
 
-		if r, exists := s.rows.Get(symbol); exists {
-			c := r.(*row).constant
-			variable.Value = c
-		} else {
-			variable.Value = 0
+		variable.Value = 0
+		if owner, exists := s.owners.Get(variable); exists {
+			if r, exists := owner.(*component).rows.Get(symbol); exists {
+				variable.Value = r.constant
+			}
+		}
+	})
+	s.updateAliases()
+
+	var err error
+	s.stays.Each(func(k, v interface{}) {
+		stay := v.(*editInfo)
+		if serr := stay.tag.owner.suggestValue(stay, k.(*Variable).Value); serr != nil && err == nil {
+			err = serr
 		}
 	})
+	return err
+}
+
+// Add a weak stay holding the variable at its current value.
+// The stay is an edit constraint of its own, so moving it later
+// is a simple suggestion.
+func (s *Solver) addStay(v *Variable) error {
+	if _, exists := s.stays.Get(v); exists {
+		return nil
+	}
+
+	c := NewConstraint(NewExpressionFrom(NewTermFrom(v)), OP_EQ, Weak)
+	if err := s.addConstraint(c, true); err != nil {
+		return err
+	}
+
+	t, _ := s.cns.Get(c)
+	stay := newEditInfo(c, t.(*tag), 0)
+	s.stays.Put(v, stay)
+	if err := stay.tag.owner.suggestValue(stay, v.Value); err != nil {
+		if rerr := s.removeStay(v); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove the stay of the variable.
+func (s *Solver) removeStay(v *Variable) error {
+	x, exists := s.stays.Get(v)
+	if !exists {
+		return nil
+	}
+	s.stays.Remove(v)
+	return s.dropConstraint(x.(*editInfo).constraint)
 }
 
 // Create a new Row object for the given constraint.
@@ -321,7 +756,7 @@ func (s *Solver) UpdateVariables() {
 //
 // The tag will be updated with the marker and error symbols to use
 // for tracking the movement of the constraint in the tableau.
-func (s *Solver) createRow(c *Constraint, tag *tag) (*row, error) {
+func (s *Solver) createRow(cp *component, c *Constraint, tag *tag) (*row, error) {
 	if c == nil {
 		return nil, errors.New("constraint is nil")
 	}
@@ -330,20 +765,24 @@ func (s *Solver) createRow(c *Constraint, tag *tag) (*row, error) {
 		return nil, errors.New("constraint doesn't have expression")
 	}
 
-	r := newRowWith(c.expression.Constant)
-	for _, t := range c.expression.Terms {
-		if !FloatNearZero(t.Coefficient) {
+	rc := s.resolved(c)
+	e, scale := rc.expression, rc.scale()
+	tag.scale = scale
+	r := newRowWith(e.Constant * scale)
+	for _, t := range e.Terms {
+		if coefficient := t.Coefficient * scale; !FloatNearZero(coefficient) {
 			symbol := s.varSymbol(t.Variable)
-			if otherRow, exists := s.rows.Get(symbol); exists {
-				r.insertRow(otherRow.(*row), t.Coefficient)
+			if otherRow, exists := cp.rows.Get(symbol); exists {
+				r.insertRow(otherRow, coefficient)
 			} else {
-				r.insertSymbol(symbol, t.Coefficient)
+				r.insertSymbol(symbol, coefficient)
 			}
 		}
 	}
 
 	switch c.Op {
-	case OP_LE, OP_GE:
+	case OP_LE, OP_GE, OP_RANGE:
+		// The first row of a range is its lower bound.
 		coeff := float64(-1)
 		if c.Op == OP_LE {
 			coeff = 1
@@ -355,7 +794,7 @@ func (s *Solver) createRow(c *Constraint, tag *tag) (*row, error) {
 			serror := newSymbolFrom(symbolError)
 			tag.other = serror
 			r.insertSymbol(serror, -coeff)
-			s.objective.insertSymbol(serror, float64(c.Strength))
+			cp.objective.insertSymbol(serror, tag.weight(c))
 		}
 
 	case OP_EQ:
@@ -366,8 +805,8 @@ func (s *Solver) createRow(c *Constraint, tag *tag) (*row, error) {
 			tag.other = errMinus
 			r.insertSymbol(errPlus, -1) // v = eplus - eminus
 			r.insertSymbol(errMinus, 1) // v - eplus + eminus = 0
-			s.objective.insertSymbol(errPlus, float64(c.Strength))
-			s.objective.insertSymbol(errMinus, float64(c.Strength))
+			cp.objective.insertSymbol(errPlus, tag.weight(c))
+			cp.objective.insertSymbol(errMinus, tag.weight(c))
 		} else {
 			dummy := newSymbolFrom(symbolDummy)
 			tag.marker = dummy
@@ -383,246 +822,19 @@ func (s *Solver) createRow(c *Constraint, tag *tag) (*row, error) {
 	return r, nil
 }
 
-// Choose the subject for solving for the row
-// This method will choose the best subject for using as the solve
-// target for the row. An invalid symbol will be returned if there
-// is no valid target.
-// The symbols are chosen according to the following precedence:
-// 1) The first symbol representing an external variable.
-// 2) A negative slack or error tag variable.
-// If a subject cannot be found, an invalid symbol will be returned.
-func (s *Solver) chooseSubject(r *row, t *tag) *symbol {
-	if fk, _ := r.cells.Find(func(k, v interface{}) bool {
-		return k.(*symbol).kind == symbolExternal
-	}); fk != nil {
-		return fk.(*symbol)
-	}
-
-	if t.marker != nil && (t.marker.kind == symbolSlack || t.marker.kind == symbolError) {
-		if r.coefficientFor(t.marker) < 0.0 {
-			return t.marker
-		}
-	}
-
-	if t.other != nil && (t.other.kind == symbolSlack || t.other.kind == symbolError) {
-		if r.coefficientFor(t.other) < 0.0 {
-			return t.other
-		}
+// Get the power of two which brings the largest coefficient of the
+// expression into [1, 2). Multiplying by a power of two is exact, so
+// scaling a row this way changes no digits, only the exponents.
+func rowScale(e *Expression) float64 {
+	largest := float64(0)
+	for _, t := range e.Terms {
+		largest = math.Max(largest, math.Abs(t.Coefficient))
 	}
-
-	return newSymbol()
-}
-
-// Add the row to the tableau using an artificial variable.
-// This will return false if the constraint cannot be satisfied.
-func (s *Solver) addWithArtificialVariable(r *row) (bool, error) {
-	// Create and add the artificial variable to the tableau
-	art := newSymbolFrom(symbolSlack)
-	s.rows.Put(art, newRowFrom(r))
-	s.artificial = newRowFrom(r)
-
-	// Optimize the artificial objective. This is successful
-	// only if the artificial objective is optimized to zero.
-	if err := s.optimize(s.artificial); err != nil {
-		return false, errors.Wrap(err, "can't optimize")
+	if largest == 0 {
+		return 1
 	}
-
-	success := FloatNearZero(s.artificial.constant)
-	s.artificial = nil
-
-	// If the artificial variable is basic, pivot the row so that
-	// it becomes basic. If the row is constant, exit early.
-	if x, exists := s.rows.Get(art); exists {
-		/**this looks wrong!!!*/
-		//rows.remove(rowptr);
-		rowptr := x.(*row)
-		s.rows = s.rows.Select(func(k, v interface{}) bool {
-			return v.(*row) != rowptr
-		})
-
-		if rowptr.cells.Size() == 0 {
-			return success, nil
-		}
-
-		entering := s.anyPivotableSymbol(rowptr)
-		if entering.kind == symbolInvalid {
-			return false, nil // unsatisfiable (will this ever happen?)
-		}
-		rowptr.solveForSymbols(art, entering)
-		s.substitute(entering, rowptr)
-		s.rows.Put(entering, rowptr)
-	}
-
-	// Remove the artificial variable from the tableau.
-	s.rows.Each(func(k, v interface{}) {
-		v.(*row).cells.Remove(art)
-	})
-
-	s.objective.cells.Remove(art)
-	return success, nil
-}
-
-// Substitute the parametric symbol with the given row.
-// This method will substitute all instances of the parametric symbol
-// in the tableau and the objective function with the given row.
-func (s *Solver) substitute(sym *symbol, r *row) {
-	s.rows.Each(func(k, v interface{}) {
-		ss := k.(*symbol)
-		row := v.(*row)
-		row.substitute(sym, r)
-
-		if ss.kind != symbolExternal && row.constant < 0 {
-			s.infeasibleRows = append(s.infeasibleRows, ss)
-		}
-	})
-
-	s.objective.substitute(sym, r)
-
-	if s.artificial != nil {
-		s.artificial.substitute(sym, r)
-	}
-}
-
-// Optimize the system for the given objective function.
-// This method performs iterations of Phase 2 of the simplex method
-// until the objective function reaches a minimum.
-func (s *Solver) optimize(objective *row) error {
-	for {
-		entering := s.enteringSymbol(objective)
-		if entering.kind == symbolInvalid {
-			return nil
-		}
-
-		entry := s.leavingRow(entering)
-		if entry == nil {
-			return errors.New("The objective is unbounded.")
-		}
-
-		var leaving, entryKey *symbol
-		{
-			if fs, _ := s.rows.Find(func(k, v interface{}) bool {
-				return v.(*row) == entry
-			}); fs != nil {
-				leaving = fs.(*symbol)
-			}
-
-			if fs, _ := s.rows.Find(func(k, v interface{}) bool {
-				return v.(*row) == entry
-			}); fs != nil {
-				entryKey = fs.(*symbol)
-			}
-		}
-
-		s.rows.Remove(entryKey)
-		entry.solveForSymbols(leaving, entering)
-		s.substitute(entering, entry)
-		s.rows.Put(entering, entry)
-	}
-}
-
-func (s *Solver) dualOptimize() error {
-	for len(s.infeasibleRows) > 0 {
-		lastIndex := len(s.infeasibleRows) - 1
-		leaving := s.infeasibleRows[lastIndex]
-		s.infeasibleRows = s.infeasibleRows[:lastIndex]
-
-		if x, exists := s.rows.Get(leaving); exists {
-			r := x.(*row)
-			if r.constant < 0 {
-				entering := s.dualEnteringSymbol(r)
-				if entering.kind == symbolInvalid {
-					return InternalSolverErr
-				}
-				s.rows.Remove(leaving)
-				r.solveForSymbols(leaving, entering)
-				s.substitute(entering, r)
-				s.rows.Put(entering, r)
-			}
-		}
-	}
-	return nil
-}
-
-// Compute the entering variable for a pivot operation.
-// This method will return first symbol in the objective function which
-// is non-dummy and has a coefficient less than zero. If no symbol meets
-// the criteria, it means the objective function is at a minimum, and an
-// invalid symbol is returned.
-func (s *Solver) enteringSymbol(objective *row) *symbol {
-	foundSymbolRaw, _ := objective.cells.Find(func(k, v interface{}) bool {
-		symbol := k.(*symbol)
-		value := v.(float64)
-
-		if symbol.kind != symbolDummy && value < 0 {
-			return true
-		}
-		return false
-	})
-	if foundSymbolRaw != nil {
-		return foundSymbolRaw.(*symbol)
-	}
-
-	return newSymbol()
-}
-
-func (s *Solver) dualEnteringSymbol(r *row) *symbol {
-	entering, ratio := newSymbol(), math.MaxFloat64
-	r.cells.Each(func(k, v interface{}) {
-		if sym := k.(*symbol); sym.kind != symbolDummy {
-			x, _ := r.cells.Get(sym)
-			currentCell := x.(float64)
-			if currentCell > 0.0 {
-				coefficient := s.objective.coefficientFor(sym)
-				r := coefficient / currentCell
-				if r < ratio {
-					ratio = r
-					entering = sym
-				}
-			}
-		}
-	})
-
-	return entering
-}
-
-// Get the first Slack or Error symbol in the row.
-// If no such symbol is present, and Invalid symbol will be returned.
-func (s *Solver) anyPivotableSymbol(r *row) *symbol {
-	if fs, _ := r.cells.Find(func(k, v interface{}) bool {
-		sym := k.(*symbol)
-		return sym.kind == symbolSlack || sym.kind == symbolError
-	}); fs != nil {
-		return fs.(*symbol)
-	}
-	return newSymbol()
-}
-
-// Compute the row which holds the exit symbol for a pivot.
-// This documentation is copied from the C++ version and is outdated
-// This method will return an iterator to the row in the row map
-// which holds the exit symbol. If no appropriate exit symbol is
-// found, the end() iterator will be returned. This indicates that
-// the objective function is unbounded.
-func (s *Solver) leavingRow(entering *symbol) *row {
-	ratio := math.MaxFloat64
-	var r *row
-
-	s.rows.Each(func(k, v interface{}) {
-		sym := k.(*symbol)
-
-		if sym.kind != symbolExternal {
-			candidate := v.(*row)
-
-			t := candidate.coefficientFor(entering)
-			if t < 0 {
-				if tr := -candidate.constant / t; tr < ratio {
-					ratio = tr
-					r = candidate
-				}
-			}
-		}
-	})
-	return r
+	_, exp := math.Frexp(largest)
+	return math.Ldexp(1, 1-exp)
 }
 
 // Get the symbol for the given variable.
@@ -0,0 +1,58 @@
+package cassgowary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImplicitStaysKeepValues(t *testing.T) {
+	solver := NewSolver(WithImplicitStays())
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	cx := x.EqualsFloat(10)
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(5))))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.InDelta(t, 15, y.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+	assert.InDelta(t, 15, y.Value, Epsilon)
+}
+
+func TestImplicitStaysFollowEdits(t *testing.T) {
+	solver := NewSolver(WithImplicitStays())
+	x := NewVariable("x")
+	width := NewVariable("width")
+
+	assert.NoError(t, solver.AddConstraint(width.EqualsFloat(100)))
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(0)))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 40))
+	solver.UpdateVariables()
+	assert.InDelta(t, 40, x.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveEditVariable(x))
+	solver.UpdateVariables()
+	assert.InDelta(t, 40, x.Value, Epsilon)
+	assert.InDelta(t, 100, width.Value, Epsilon)
+}
+
+func TestWithoutImplicitStays(t *testing.T) {
+	solver := NewSolver()
+	x := NewVariable("x")
+
+	cx := x.EqualsFloat(10)
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(0)))
+	solver.UpdateVariables()
+	assert.InDelta(t, 10, x.Value, Epsilon)
+
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	solver.UpdateVariables()
+	assert.InDelta(t, 0, x.Value, Epsilon)
+}
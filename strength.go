@@ -9,6 +9,10 @@ var (
 	Strong   = CreateStrengthWithDefaultWeight(1, 0, 0)
 	Medium   = CreateStrengthWithDefaultWeight(0, 1, 0)
 	Weak     = CreateStrengthWithDefaultWeight(0, 0, 1)
+
+	// AlmostRequired is the strength conflicting required constraints
+	// are added with when the solver recovers from them.
+	AlmostRequired = CreateStrengthWithDefaultWeight(999, 0, 0)
 )
 
 func CreateStrength(a, b, c, w float64) Strength {
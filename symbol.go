@@ -1,5 +1,7 @@
 package cassgowary
 
+import "strconv"
+
 type symbols []*symbol
 
 type symbolType int
@@ -33,3 +35,23 @@ func newSymbolFrom(t symbolType) *symbol {
 	s.kind = t
 	return s
 }
+
+var symbolPrefixes = map[symbolType]string{
+	symbolInvalid:  "i",
+	symbolExternal: "v",
+	symbolSlack:    "s",
+	symbolError:    "e",
+	symbolDummy:    "d",
+}
+
+var symbolKindNames = map[symbolType]string{
+	symbolInvalid:  "invalid",
+	symbolExternal: "variable",
+	symbolSlack:    "slack",
+	symbolError:    "error",
+	symbolDummy:    "dummy",
+}
+
+func (s *symbol) String() string {
+	return symbolPrefixes[s.kind] + strconv.Itoa(s.id)
+}
@@ -0,0 +1,342 @@
+package cassgowary
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Expansions nested deeper than this are rejected, as a template calling
+// itself would never end.
+const maxExpansionDepth = 100
+
+// Loops running more often than this are rejected.
+const maxLoopIterations = 100000
+
+// A template of a document, expanded by calling it.
+type template struct {
+	name   string
+	params []string
+	body   []sourceLine
+}
+
+// The state of reading a document, with its templates and the blocks
+// being expanded.
+type expansion struct {
+	document  *Document
+	scope     *documentScope
+	templates map[string]*template
+	// The expansions being run, outermost first, for the error messages.
+	context []string
+}
+
+// Run the statements of the lines. The errors are located in the
+// document already.
+func (x *expansion) run(lines []sourceLine) error {
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		p, err := l.parser(x.scope)
+		if err != nil {
+			return x.locate(err, l)
+		}
+		if p == nil {
+			continue
+		}
+
+		switch {
+		case isBlockEnd(p):
+			return x.locate(p.errorf(p.tokens[0], "unexpected '}'"), l)
+		case isBlockStart(p):
+			end, err := blockEnd(lines, i)
+			if err != nil {
+				return x.locate(p.errorf(p.tokens[len(p.tokens)-2], "%v", err), l)
+			}
+			if err := x.block(p, l, lines[i+1:end]); err != nil {
+				return err
+			}
+			i = end
+		case x.called(p) != nil:
+			if err := x.call(p, x.called(p), l); err != nil {
+				return err
+			}
+		default:
+			if err := x.statement(p, l); err != nil {
+				return x.locate(err, l)
+			}
+		}
+	}
+	return nil
+}
+
+// Move an error of a line to its place in the document.
+func (x *expansion) locate(err error, l sourceLine) error {
+	if pe, ok := err.(*ParseError); ok {
+		pe.Line = l.line
+		pe.Offset += l.offset
+		context := x.context
+		if len(context) > 4 {
+			// Deep recursion would make the message as long.
+			context = append(append(context[:2:2], "..."), context[len(context)-2:]...)
+		}
+		if len(context) > 0 {
+			pe.Message += " (in " + strings.Join(context, ", in ") + ")"
+		}
+	}
+	return err
+}
+
+// A line ending with a '{' opens a block.
+func isBlockStart(p *parser) bool {
+	last := p.tokens[len(p.tokens)-2]
+	return last.kind == tokenPunctuation && last.text == "{"
+}
+
+// A line with just a '}' closes a block.
+func isBlockEnd(p *parser) bool {
+	first := p.tokens[0]
+	return len(p.tokens) == 2 && first.kind == tokenPunctuation && first.text == "}"
+}
+
+// Find the line closing the block opened at lines[start].
+func blockEnd(lines []sourceLine, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		// Lines which don't lex are neither, their errors come when
+		// they are run.
+		p, err := lines[i].parser(MapResolver{})
+		if err != nil || p == nil {
+			continue
+		}
+		switch {
+		case isBlockStart(p):
+			depth++
+		case isBlockEnd(p):
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("'{' is never closed")
+}
+
+// Declare a template or run a loop, the header of which is parsed by p.
+func (x *expansion) block(p *parser, l sourceLine, body []sourceLine) error {
+	if err := x.checkNoTemplates(body); err != nil {
+		return err
+	}
+	keyword := p.next()
+	var err error
+	switch {
+	case keyword.kind == tokenIdentifier && keyword.text == "template":
+		err = x.templateStatement(p, body)
+	case keyword.kind == tokenIdentifier && keyword.text == "for":
+		var header *loop
+		if header, err = p.forStatement(); err == nil {
+			return x.loop(header, body, l)
+		}
+	default:
+		err = p.expected(keyword, "template or for")
+	}
+	return x.locate(err, l)
+}
+
+// Templates are declared at the top level of the document only, so the
+// templates a line can call don't depend on what ran before it.
+func (x *expansion) checkNoTemplates(lines []sourceLine) error {
+	for _, l := range lines {
+		p, err := l.parser(MapResolver{})
+		if err != nil || p == nil || !isBlockStart(p) {
+			continue
+		}
+		if keyword := p.tokens[0]; keyword.kind == tokenIdentifier && keyword.text == "template" {
+			return x.locate(p.errorf(keyword, "templates can only be declared at the top level"), l)
+		}
+	}
+	return nil
+}
+
+// template name(param, ...) {
+func (x *expansion) templateStatement(p *parser, body []sourceLine) error {
+	name := p.next()
+	if name.kind != tokenIdentifier {
+		return p.expected(name, "template name")
+	}
+	if _, exists := piecewiseKinds[name.text]; exists {
+		return p.errorf(name, "%s is a function", name)
+	}
+	if _, exists := x.templates[name.text]; exists {
+		return p.errorf(name, "template %s is already declared", name)
+	}
+	if !p.accept("(") {
+		return p.expected(p.peek(), "'('")
+	}
+
+	t := &template{name: name.text, body: body}
+	for !p.accept(")") {
+		if len(t.params) > 0 && !p.accept(",") {
+			return p.expected(p.peek(), "',' or ')'")
+		}
+		param := p.next()
+		if param.kind != tokenIdentifier || strings.ContainsRune(param.text, '{') {
+			return p.expected(param, "parameter name")
+		}
+		for _, other := range t.params {
+			if other == param.text {
+				return p.errorf(param, "parameter %s is already declared", param)
+			}
+		}
+		t.params = append(t.params, param.text)
+	}
+	if !p.accept("{") {
+		return p.expected(p.peek(), "'{'")
+	}
+	x.templates[t.name] = t
+	return nil
+}
+
+// The header of a for loop.
+type loop struct {
+	variable string
+	from, to int
+}
+
+// for name in from..to {
+func (p *parser) forStatement() (*loop, error) {
+	name := p.next()
+	if name.kind != tokenIdentifier || strings.ContainsRune(name.text, '{') {
+		return nil, p.expected(name, "loop variable")
+	}
+	if in := p.next(); in.kind != tokenIdentifier || in.text != "in" {
+		return nil, p.expected(in, "'in'")
+	}
+	from, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("..") {
+		return nil, p.expected(p.peek(), "'..'")
+	}
+	t := p.peek()
+	to, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	if to-from >= maxLoopIterations {
+		return nil, p.errorf(t, "the loop runs more than %d times", maxLoopIterations)
+	}
+	if !p.accept("{") {
+		return nil, p.expected(p.peek(), "'{'")
+	}
+	return &loop{variable: name.text, from: from, to: to}, nil
+}
+
+// Parse a constant expression which has to be an integer.
+func (p *parser) integer() (int, error) {
+	t := p.peek()
+	value, err := p.constant()
+	if err != nil {
+		return 0, err
+	}
+	if value != math.Trunc(value) || math.Abs(value) > math.MaxInt32 {
+		return 0, p.errorf(t, "expected integer, found %g", value)
+	}
+	return int(value), nil
+}
+
+// Run the body for each value of the loop variable.
+func (x *expansion) loop(header *loop, body []sourceLine, l sourceLine) error {
+	for i := header.from; i <= header.to; i++ {
+		err := x.expand(map[string]float64{header.variable: float64(i)}, body,
+			fmt.Sprintf("for %s = %d at line %d", header.variable, i, l.line))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the template called by the line, if it is a call.
+func (x *expansion) called(p *parser) *template {
+	first, second := p.tokens[0], p.tokens[1]
+	if first.kind != tokenIdentifier || second.kind != tokenPunctuation || second.text != "(" {
+		return nil
+	}
+	return x.templates[first.text]
+}
+
+// Expand the template called by the line.
+func (x *expansion) call(p *parser, t *template, l sourceLine) error {
+	args, err := x.arguments(p, t)
+	if err != nil {
+		return x.locate(err, l)
+	}
+
+	bindings := map[string]float64{}
+	formatted := make([]string, len(args))
+	for i, param := range t.params {
+		bindings[param] = args[i]
+		formatted[i] = formatNumber(args[i])
+	}
+
+	// The body sees its parameters and the constants of the document,
+	// but not the loop variables and parameters of the caller.
+	caller := x.scope.bindings
+	x.scope.bindings = map[string]float64{}
+	err = x.expand(bindings, t.body,
+		fmt.Sprintf("%s(%s) at line %d", t.name, strings.Join(formatted, ", "), l.line))
+	x.scope.bindings = caller
+	return err
+}
+
+// name(argument, ...)
+func (x *expansion) arguments(p *parser, t *template) ([]float64, error) {
+	name := p.next()
+	p.next()
+	var args []float64
+	for !p.accept(")") {
+		if len(args) > 0 && !p.accept(",") {
+			return nil, p.expected(p.peek(), "',' or ')'")
+		}
+		arg, err := p.constant()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	if len(args) != len(t.params) {
+		return nil, p.errorf(name, "template %s takes %d arguments, found %d", name, len(t.params), len(args))
+	}
+	if len(x.context) >= maxExpansionDepth {
+		return nil, p.errorf(name, "templates nested too deeply")
+	}
+	return args, nil
+}
+
+// Run the lines with the names bound, restoring the outer bindings after.
+func (x *expansion) expand(bindings map[string]float64, lines []sourceLine, context string) error {
+	outer := map[string]float64{}
+	for name := range bindings {
+		if value, exists := x.scope.bindings[name]; exists {
+			outer[name] = value
+		}
+	}
+	for name, value := range bindings {
+		x.scope.bindings[name] = value
+	}
+	x.context = append(x.context, context)
+
+	err := x.run(lines)
+
+	x.context = x.context[:len(x.context)-1]
+	for name := range bindings {
+		if value, exists := outer[name]; exists {
+			x.scope.bindings[name] = value
+		} else {
+			delete(x.scope.bindings, name)
+		}
+	}
+	return err
+}
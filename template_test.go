@@ -0,0 +1,192 @@
+package cassgowary
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The grid of gridDocument, with the repeated lines in templates and loops.
+const gridTemplateDocument = `let rowPadding = 15
+let thumbRatio = 1 / 2
+
+var container.width = 300
+edit container.width !strong
+
+container.columnWidth == container.width * 0.4
+container.thumbHeight == container.columnWidth * thumbRatio
+container.padding == container.width * (0.2 / 3)
+container.leftPadding == container.padding
+container.rightPadding == container.width - container.padding
+container.paddingUnderThumb == 5
+container.rowPadding == rowPadding
+
+template thumb(i) {
+	thumb{i}.top == container.padding
+	thumb{i}.height == container.thumbHeight
+	thumb{i}.width == container.columnWidth
+}
+
+template title(i, row, height) {
+	title{i}.top == thumb{row}.bottom + container.paddingUnderThumb
+	title{i}.height == height
+	title{i}.width == container.columnWidth
+}
+
+for i in 0..1 {
+	thumb(i)
+	title(i, 0, 100 + 10 * i)
+}
+thumb0.left == container.leftPadding
+title0.left == container.leftPadding
+thumb1.right == container.rightPadding
+title1.right == container.rightPadding
+
+thumb2.left == container.leftPadding
+for i in 0..1 {
+	thumb2.top >= title{i}.bottom + container.rowPadding
+	thumb2.top == title{i}.bottom + container.rowPadding !weak
+}
+`
+
+func formattedConstraints(d *Document) []string {
+	var formatted []string
+	for _, dc := range d.Constraints {
+		formatted = append(formatted, Format(dc.Constraint))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+func TestParseTemplateDocument(t *testing.T) {
+	parser := NewConstraintParser()
+	expected, err := parser.ParseDocument(strings.NewReader(gridDocument), MapResolver{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	solver := NewSolver()
+	vr := NewNodeResolver(solver)
+	d, err := parser.ParseDocument(strings.NewReader(gridTemplateDocument), vr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, formattedConstraints(expected), formattedConstraints(d))
+	assert.Equal(t, expected.Constants, d.Constants)
+	if assert.Len(t, d.Constraints, 28) {
+		assert.Equal(t, 16, d.Constraints[7].Line)
+		assert.Equal(t, "thumb{i}.top == container.padding", d.Constraints[7].Source)
+	}
+
+	assert.NoError(t, d.AddTo(solver))
+	solver.UpdateVariables()
+	assert.InDelta(t, 20, vr.Nodes["thumb0"]["top"].Value, Epsilon)
+	assert.InDelta(t, 85, vr.Nodes["title1"]["top"].Value, Epsilon)
+	assert.InDelta(t, 210, vr.Nodes["thumb2"]["top"].Value, Epsilon)
+}
+
+func TestParseTemplateNesting(t *testing.T) {
+	vr := MapResolver{}
+	d, err := NewConstraintParser().ParseDocument(strings.NewReader(`let n = 3
+template gap(a, b) {
+	cell{b}.left >= cell{a}.right + 10
+}
+template row(i) {
+	for j in i..n - 1 {
+		gap(j - 1, j)
+	}
+}
+row(1)
+var cell{n - 1}.left = 5
+edit cell{n}.left
+`), vr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"-cell0.right + cell1.left >= 10",
+		"-cell1.right + cell2.left >= 10",
+	}, formattedConstraints(d))
+	if assert.Len(t, d.Variables, 1) {
+		assert.Equal(t, vr["cell2.left"], d.Variables[0])
+		assert.Equal(t, 5.0, d.Variables[0].Value)
+	}
+	if assert.Len(t, d.Edits, 1) {
+		assert.Equal(t, vr["cell3.left"], d.Edits[0].Variable)
+	}
+	assert.Equal(t, map[string]float64{"n": 3}, d.Constants)
+
+	d, err = NewConstraintParser().ParseDocument(strings.NewReader("for i in 2..1 {\n\tx{i} == 0\n}\n"), vr)
+	assert.NoError(t, err)
+	assert.Empty(t, d.Constraints)
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		document     string
+		line, column int
+		message      string
+	}{
+		{"template card(i) {\n\tthumb{i}.top == (\n}\ncard(3)", 2, 19,
+			"expected expression, found end of input (in card(3) at line 4)"},
+		{"template a(i) {\n\tx{i} == 1 +\n}\ntemplate b(i) {\n\ta(i + 1)\n}\nfor k in 0..2 {\n\tb(k)\n}", 2, 13,
+			"expected expression, found end of input (in for k = 0 at line 7, in b(0) at line 8, in a(1) at line 5)"},
+		{"for i in 0..1 {\n\tthumb{i / 2}.top == 0\n}", 2, 8,
+			"interpolated value 0.5 is not an integer (in for i = 1 at line 1)"},
+		{"x{}.top == 0", 1, 3, "expected expression in '{}'"},
+		{"x{y}.top == 0", 1, 3, "expected constant expression"},
+		{"for i in 0..3 {\n\tx == i\n", 1, 15, "'{' is never closed"},
+		{"x == 1\n}", 2, 1, "unexpected '}'"},
+		{"for i in 0..x {\n}", 1, 13, "expected constant expression"},
+		{"for i in 0.5..3 {\n}", 1, 10, "expected integer, found 0.5"},
+		{"for i of 0..3 {\n}", 1, 7, "expected 'in', found 'of'"},
+		{"while x {\n}", 1, 1, "expected template or for, found 'while'"},
+		{"template max(i) {\n}", 1, 10, "'max' is a function"},
+		{"template a(i, i) {\n}", 1, 15, "parameter 'i' is already declared"},
+		{"template a(i) {\n}\ntemplate a(j) {\n}", 3, 10, "template 'a' is already declared"},
+		{"template a(i)\n", 1, 14, "expected '{' at the end of the line, found end of input"},
+		{"template a(i, j) {\n}\na(1)", 3, 1, "template 'a' takes 2 arguments, found 1"},
+		{"template a(i) {\n}\na(x)", 3, 3, "expected constant expression"},
+		{"for i in 0..1 {\n\ttemplate a(j) {\n\t}\n}", 2, 2, "templates can only be declared at the top level"},
+		{"template a(i) {\n\tfor j in 0..i {\n\t\ttemplate b(k) {\n\t\t}\n\t}\n}", 3, 3,
+			"templates can only be declared at the top level"},
+		{"template a(i) {\n\tx{j} == i\n}\nfor j in 0..1 {\n\ta(j)\n}", 2, 4,
+			"expected constant expression (in for j = 0 at line 4, in a(0) at line 5)"},
+		{"template a(i) {\n\ta(i + 1)\n}\na(0)", 2, 2,
+			"templates nested too deeply (in a(0) at line 4, in a(1) at line 2, in ..., in a(98) at line 2, in a(99) at line 2)"},
+	} {
+		_, err := NewConstraintParser().ParseDocument(strings.NewReader(tc.document), MapResolver{})
+		pe, ok := err.(*ParseError)
+		if !assert.True(t, ok, tc.document) {
+			continue
+		}
+		assert.Equal(t, tc.line, pe.Line, tc.document)
+		assert.Equal(t, tc.column, pe.Column, tc.document)
+		assert.Equal(t, tc.message, pe.Message, tc.document)
+	}
+}
+
+func TestLexRanges(t *testing.T) {
+	for input, expected := range map[string][]string{
+		"0..5":          {"0", "..", "5"},
+		"n..m":          {"n", "..", "m"},
+		"1.5":           {"1.5"},
+		"thumb{i-2}.x":  {"thumb{i-2}.x"},
+		"n{":            {"n", "{"},
+		"a{b{c}} == 1":  {"a", "{", "b{c}", "}", "==", "1"},
+		"card(i) {":     {"card", "(", "i", ")", "{"},
+		"x{i}{j}.left ": {"x{i}{j}.left"},
+	} {
+		tokens, err := lex(input)
+		if !assert.NoError(t, err, input) {
+			continue
+		}
+		var texts []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			texts = append(texts, tok.text)
+		}
+		assert.Equal(t, expected, texts, input)
+	}
+}
@@ -0,0 +1,197 @@
+package cassgowary
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer receives callbacks while the solver works.
+// Symbols are reported by name: v for external variables, s for slack,
+// e for error and d for dummy symbols, followed by the symbol id.
+type Tracer interface {
+	// ConstraintAdded is called once a constraint is in the tableau.
+	ConstraintAdded(c *Constraint)
+	// ConstraintRemoved is called once a constraint left the tableau.
+	ConstraintRemoved(c *Constraint)
+	// RowCreated is called with the row built for a constraint.
+	RowCreated(c *Constraint, row string)
+	// SubjectChosen is called with the symbol the new row is solved for.
+	// An invalid symbol means the row is added with an artificial variable.
+	SubjectChosen(c *Constraint, subject string)
+	// Pivot is called for every pivot of the tableau.
+	Pivot(entering, leaving string, ratio float64)
+	// ArtificialStart is called before the artificial phase for a row.
+	ArtificialStart(c *Constraint)
+	// ArtificialEnd is called after the artificial phase for a row.
+	ArtificialEnd(c *Constraint, success bool)
+	// DualIteration is called for every iteration of the dual optimization.
+	DualIteration(leaving, entering string)
+	// EditSuggested is called for every suggested edit value.
+	EditSuggested(v *Variable, value float64)
+}
+
+type slogTracer struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogTracer returns a tracer logging every callback to the logger
+// at the given level.
+func NewSlogTracer(logger *slog.Logger, level slog.Level) Tracer {
+	return &slogTracer{
+		logger: logger,
+		level:  level,
+	}
+}
+
+func (t *slogTracer) log(msg string, attrs ...slog.Attr) {
+	t.logger.LogAttrs(context.Background(), t.level, msg, attrs...)
+}
+
+func (t *slogTracer) ConstraintAdded(c *Constraint) {
+	t.log("constraint added", slog.String("constraint", c.String()))
+}
+
+func (t *slogTracer) ConstraintRemoved(c *Constraint) {
+	t.log("constraint removed", slog.String("constraint", c.String()))
+}
+
+func (t *slogTracer) RowCreated(c *Constraint, row string) {
+	t.log("row created", slog.String("constraint", c.String()), slog.String("row", row))
+}
+
+func (t *slogTracer) SubjectChosen(c *Constraint, subject string) {
+	t.log("subject chosen", slog.String("constraint", c.String()), slog.String("subject", subject))
+}
+
+func (t *slogTracer) Pivot(entering, leaving string, ratio float64) {
+	t.log("pivot",
+		slog.String("entering", entering),
+		slog.String("leaving", leaving),
+		slog.Float64("ratio", ratio),
+	)
+}
+
+func (t *slogTracer) ArtificialStart(c *Constraint) {
+	t.log("artificial phase start", slog.String("constraint", c.String()))
+}
+
+func (t *slogTracer) ArtificialEnd(c *Constraint, success bool) {
+	t.log("artificial phase end", slog.String("constraint", c.String()), slog.Bool("success", success))
+}
+
+func (t *slogTracer) DualIteration(leaving, entering string) {
+	t.log("dual optimize", slog.String("leaving", leaving), slog.String("entering", entering))
+}
+
+func (t *slogTracer) EditSuggested(v *Variable, value float64) {
+	t.log("edit suggested", slog.String("variable", v.Name), slog.Float64("value", value))
+}
+
+// TraceKind identifies the callback a TraceEvent was recorded from.
+type TraceKind int
+
+const (
+	TraceConstraintAdded TraceKind = iota
+	TraceConstraintRemoved
+	TraceRowCreated
+	TraceSubjectChosen
+	TracePivot
+	TraceArtificialStart
+	TraceArtificialEnd
+	TraceDualIteration
+	TraceEditSuggested
+)
+
+var traceKindNames = map[TraceKind]string{
+	TraceConstraintAdded:   "constraint added",
+	TraceConstraintRemoved: "constraint removed",
+	TraceRowCreated:        "row created",
+	TraceSubjectChosen:     "subject chosen",
+	TracePivot:             "pivot",
+	TraceArtificialStart:   "artificial phase start",
+	TraceArtificialEnd:     "artificial phase end",
+	TraceDualIteration:     "dual optimize",
+	TraceEditSuggested:     "edit suggested",
+}
+
+func (k TraceKind) String() string {
+	return traceKindNames[k]
+}
+
+// TraceEvent is a single callback recorded by a RecordingTracer.
+// Only the fields relevant for the kind of event are set.
+type TraceEvent struct {
+	Kind       TraceKind
+	Constraint *Constraint
+	Row        string
+	Subject    string
+	Entering   string
+	Leaving    string
+	Ratio      float64
+	Success    bool
+	Variable   *Variable
+	Value      float64
+}
+
+// RecordingTracer keeps every callback as a TraceEvent, which is
+// mostly useful in tests.
+type RecordingTracer struct {
+	Events []TraceEvent
+}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// Count returns the number of recorded events of the given kind.
+func (t *RecordingTracer) Count(kind TraceKind) int {
+	count := 0
+	for _, e := range t.Events {
+		if e.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset forgets all recorded events.
+func (t *RecordingTracer) Reset() {
+	t.Events = t.Events[:0]
+}
+
+func (t *RecordingTracer) ConstraintAdded(c *Constraint) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceConstraintAdded, Constraint: c})
+}
+
+func (t *RecordingTracer) ConstraintRemoved(c *Constraint) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceConstraintRemoved, Constraint: c})
+}
+
+func (t *RecordingTracer) RowCreated(c *Constraint, row string) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceRowCreated, Constraint: c, Row: row})
+}
+
+func (t *RecordingTracer) SubjectChosen(c *Constraint, subject string) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceSubjectChosen, Constraint: c, Subject: subject})
+}
+
+func (t *RecordingTracer) Pivot(entering, leaving string, ratio float64) {
+	t.Events = append(t.Events, TraceEvent{Kind: TracePivot, Entering: entering, Leaving: leaving, Ratio: ratio})
+}
+
+func (t *RecordingTracer) ArtificialStart(c *Constraint) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceArtificialStart, Constraint: c})
+}
+
+func (t *RecordingTracer) ArtificialEnd(c *Constraint, success bool) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceArtificialEnd, Constraint: c, Success: success})
+}
+
+func (t *RecordingTracer) DualIteration(leaving, entering string) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceDualIteration, Entering: entering, Leaving: leaving})
+}
+
+func (t *RecordingTracer) EditSuggested(v *Variable, value float64) {
+	t.Events = append(t.Events, TraceEvent{Kind: TraceEditSuggested, Variable: v, Value: value})
+}
@@ -0,0 +1,98 @@
+package cassgowary
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingTracer(t *testing.T) {
+	tracer := NewRecordingTracer()
+	solver := NewSolver(WithTracer(tracer))
+	x := NewVariable("x")
+	y := NewVariable("y")
+
+	cx := x.GreaterThanOrEqualToFloat(10)
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(y.EqualsExpression(x.AddFloat(5))))
+	assert.NoError(t, solver.AddEditVariable(x, Strong))
+	assert.NoError(t, solver.SuggestValue(x, 20))
+	assert.NoError(t, solver.SuggestValue(x, 0))
+
+	assert.Equal(t, 3, tracer.Count(TraceConstraintAdded))
+	assert.Equal(t, 3, tracer.Count(TraceRowCreated))
+	assert.Equal(t, 3, tracer.Count(TraceSubjectChosen))
+	assert.Equal(t, 2, tracer.Count(TraceEditSuggested))
+	assert.True(t, tracer.Count(TraceDualIteration) > 0)
+	assert.True(t, tracer.Count(TracePivot) >= tracer.Count(TraceDualIteration))
+
+	first := tracer.Events[0]
+	assert.Equal(t, TraceRowCreated, first.Kind)
+	assert.Equal(t, cx, first.Constraint)
+
+	tracer.Reset()
+	assert.NoError(t, solver.RemoveConstraint(cx))
+	assert.Equal(t, 1, tracer.Count(TraceConstraintRemoved))
+}
+
+func TestRecordingTracerArtificialPhase(t *testing.T) {
+	tracer := NewRecordingTracer()
+	solver := NewSolver(WithTracer(tracer))
+	x := NewVariable("x")
+
+	assert.NoError(t, solver.AddConstraint(x.GreaterThanOrEqualToFloat(10)))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(15)))
+	assert.Error(t, solver.AddConstraint(x.LessThanOrEqualToFloat(5)))
+
+	assert.Equal(t, tracer.Count(TraceArtificialStart), tracer.Count(TraceArtificialEnd))
+	last := tracer.Events[len(tracer.Events)-1]
+	assert.Equal(t, TraceArtificialEnd, last.Kind)
+	assert.False(t, last.Success)
+}
+
+func TestRecordingTracerCountsEveryPivot(t *testing.T) {
+	tracer := NewRecordingTracer()
+	solver := NewSolver(WithTracer(tracer))
+	x, y := NewVariable("x"), NewVariable("y")
+	pivots := func(add func()) int {
+		tracer.Reset()
+		before := solver.pivots
+		add()
+		assert.Equal(t, solver.pivots-before, tracer.Count(TracePivot))
+		return tracer.Count(TracePivot)
+	}
+
+	// The marker of cx is at its bound, so it's pivoted in to be removed.
+	cx := x.GreaterThanOrEqualToFloat(10)
+	assert.NoError(t, solver.AddConstraint(cx))
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(0).NewModifyStrength(Weak)))
+	assert.Equal(t, 1, pivots(func() {
+		assert.NoError(t, solver.RemoveConstraint(cx))
+	}))
+
+	// The artificial variable stays basic and is pivoted out.
+	assert.NoError(t, solver.AddConstraint(y.GreaterThanOrEqualToFloat(10)))
+	assert.Equal(t, 1, pivots(func() {
+		assert.NoError(t, solver.AddConstraint(y.LessThanOrEqualToFloat(10)))
+	}))
+	assert.Equal(t, 1, tracer.Count(TraceArtificialStart))
+}
+
+func TestSlogTracer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	solver := NewSolver(WithTracer(NewSlogTracer(logger, slog.LevelDebug)))
+	x := NewVariable("x")
+
+	assert.NoError(t, solver.AddConstraint(x.EqualsFloat(10)))
+	assert.NoError(t, solver.AddEditVariable(x, Weak))
+	assert.NoError(t, solver.SuggestValue(x, 3))
+
+	out := buf.String()
+	assert.Contains(t, out, "constraint added")
+	assert.Contains(t, out, "row created")
+	assert.Contains(t, out, "edit suggested")
+	assert.Contains(t, out, "variable=x")
+}
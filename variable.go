@@ -7,6 +7,8 @@ import (
 type Variable struct {
 	Name  string
 	Value float64
+	// definition is set for the auxiliary variables of Min, Max and Abs.
+	definition *piecewise
 }
 
 func NewVariable(name string) *Variable {